@@ -1,6 +1,7 @@
 package tdat
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -31,7 +32,7 @@ func (b *Builder) Build() (*Model, error) {
 	for _, tb := range b.tableBuilders {
 		tables = append(tables, tb.build())
 	}
-	model := &Model{tables}
+	model := &Model{Tables: tables}
 	err := ValidateModel(model)
 	if err != nil {
 		return nil, err
@@ -39,6 +40,33 @@ func (b *Builder) Build() (*Model, error) {
 	return model, nil
 }
 
+// BuildColumnar is like Build, but the tables of the model are
+// in columnar form, see Table.IsColumnar. The rows are validated
+// and appended to the columns one by one, there is no model in
+// row form. Each call returns new tables, which do not share their
+// columns with tables of earlier calls.
+func (b *Builder) BuildColumnar() (*Model, error) {
+	tables := []*Table{}
+	tableNames := map[string]bool{}
+	for _, tb := range b.tableBuilders {
+		// validate like ValidateModel
+		err := ValidateName(tb.name)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %s", tb.name, err)
+		}
+		if tableNames[tb.name] {
+			return nil, fmt.Errorf("duplicate table %q", tb.name)
+		}
+		tableNames[tb.name] = true
+		table, err := tb.buildColumnar()
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %s", tb.name, err)
+		}
+		tables = append(tables, table)
+	}
+	return &Model{Tables: tables}, nil
+}
+
 // MustBuild is like Build, except it panics if
 // validation fails.
 func (b *Builder) MustBuild() *Model {
@@ -64,7 +92,11 @@ func newTableBuilder(name string) *TableBuilder {
 
 // AddColumn adds a new column to the table.
 func (b *TableBuilder) AddColumn(name string, columnType ValueType) {
-	b.columns = append(b.columns, &Column{name, columnType})
+	b.addColumn(&Column{Name: name, Type: columnType})
+}
+
+func (b *TableBuilder) addColumn(column *Column) {
+	b.columns = append(b.columns, column)
 }
 
 // AddIntColumn adds a new IntValue column to the table.
@@ -82,6 +114,40 @@ func (b *TableBuilder) AddStringColumn(name string) { b.AddColumn(name, StringVa
 // AddTimeColumn adds a new TimeValue column to the table.
 func (b *TableBuilder) AddTimeColumn(name string) { b.AddColumn(name, TimeValue) }
 
+// AddDecimalColumn adds a new DecimalValue column to the table.
+func (b *TableBuilder) AddDecimalColumn(name string) { b.AddColumn(name, DecimalValue) }
+
+// AddDateColumn adds a new DateValue column to the table.
+func (b *TableBuilder) AddDateColumn(name string) { b.AddColumn(name, DateValue) }
+
+// AddDurationColumn adds a new DurationValue column to the table.
+func (b *TableBuilder) AddDurationColumn(name string) { b.AddColumn(name, DurationValue) }
+
+// AddBytesColumn adds a new BytesValue column to the table.
+func (b *TableBuilder) AddBytesColumn(name string) { b.AddColumn(name, BytesValue) }
+
+// AddUUIDColumn adds a new UUIDValue column to the table.
+func (b *TableBuilder) AddUUIDColumn(name string) { b.AddColumn(name, UUIDValue) }
+
+// AddEnumColumn adds a new EnumValue column with the given symbols
+// to the table.
+func (b *TableBuilder) AddEnumColumn(name string, symbols ...string) {
+	b.addColumn(&Column{Name: name, Type: EnumValue, Symbols: symbols})
+}
+
+// AddListColumn adds a new ListValue column with elements of
+// type elemType to the table. For a list of enums, use
+// AddEnumListColumn.
+func (b *TableBuilder) AddListColumn(name string, elemType ValueType) {
+	b.addColumn(&Column{Name: name, Type: ListValue, ElemType: elemType})
+}
+
+// AddEnumListColumn adds a new ListValue column with EnumValue
+// elements with the given symbols to the table.
+func (b *TableBuilder) AddEnumListColumn(name string, symbols ...string) {
+	b.addColumn(&Column{Name: name, Type: ListValue, ElemType: EnumValue, Symbols: symbols})
+}
+
 // AddRow adds a new row to the table. It returns a RowBuilder that can be used
 // to add values to the new Row.
 func (b *TableBuilder) AddRow() *RowBuilder {
@@ -95,7 +161,29 @@ func (b *TableBuilder) build() *Table {
 	for _, rb := range b.rowBuilders {
 		rows = append(rows, rb.build())
 	}
-	return &Table{b.name, b.columns, rows}
+	return &Table{Name: b.name, Columns: b.columns, Rows: rows}
+}
+
+// buildColumnar validates the table like ValidateTable and returns it
+// in columnar form. Each row is appended to the columns when it is
+// valid, so no *Row is kept.
+func (b *TableBuilder) buildColumnar() (*Table, error) {
+	err := validateColumns(b.columns)
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Name: b.name, Columns: b.columns, Rows: []*Row{}}
+	table.initColumns()
+	row := &Row{}
+	for i, rb := range b.rowBuilders {
+		row.Values = rb.values
+		err := validateRow(b.columns, i, row)
+		if err != nil {
+			return nil, err
+		}
+		table.appendRow(row)
+	}
+	return table, nil
 }
 
 // ----------------------------------------------------
@@ -123,6 +211,24 @@ func newRowBuilder() *RowBuilder {
 //        val must be of type string
 //    for TimeValue:
 //        val must be of type time.Time
+//    for DecimalValue:
+//        val must be of type Decimal
+//    for DateValue:
+//        val must be of type time.Time, at midnight
+//    for DurationValue:
+//        val must be of type time.Duration
+//    for BytesValue:
+//        val must be of type []byte
+//    for UUIDValue:
+//        val must be of type UUID
+//    for EnumValue:
+//        val must be of type string, one of the symbols of the column
+//    for ListValue:
+//        val must be of type []*Value, with non-null elements of the
+//        element type of the column
+//    for a type registered with RegisterType:
+//        val can be of any type that the format function of the type
+//        accepts
 //
 // If the type of val does not fit the valueType properly, AddValue will panic.
 func (b *RowBuilder) AddValue(valueType ValueType, val interface{}) {
@@ -141,8 +247,25 @@ func (b *RowBuilder) AddValue(valueType ValueType, val interface{}) {
 			value.AsString = val.(string)
 		case TimeValue:
 			value.AsTime = val.(time.Time)
+		case DecimalValue:
+			value.AsCustom = val.(Decimal)
+		case DateValue:
+			value.AsTime = val.(time.Time)
+		case DurationValue:
+			value.AsInt = int64(val.(time.Duration))
+		case BytesValue:
+			value.AsCustom = val.([]byte)
+		case UUIDValue:
+			value.AsCustom = val.(UUID)
+		case EnumValue:
+			value.AsString = val.(string)
+		case ListValue:
+			value.AsCustom = val.([]*Value)
 		default:
-			panic("unknown valueType")
+			if lookupType(valueType) == nil {
+				panic("unknown valueType")
+			}
+			value.AsCustom = val
 		}
 	}
 	b.values = append(b.values, value)
@@ -168,6 +291,34 @@ func (b *RowBuilder) AddStringValue(val interface{}) { b.AddValue(StringValue, v
 // The val parameter must be nil or of type time.Time.
 func (b *RowBuilder) AddTimeValue(val interface{}) { b.AddValue(TimeValue, val) }
 
+// AddDecimalValue adds a Value of type DecimalValue.
+// The val parameter must be nil or of type Decimal.
+func (b *RowBuilder) AddDecimalValue(val interface{}) { b.AddValue(DecimalValue, val) }
+
+// AddDateValue adds a Value of type DateValue.
+// The val parameter must be nil or of type time.Time.
+func (b *RowBuilder) AddDateValue(val interface{}) { b.AddValue(DateValue, val) }
+
+// AddDurationValue adds a Value of type DurationValue.
+// The val parameter must be nil or of type time.Duration.
+func (b *RowBuilder) AddDurationValue(val interface{}) { b.AddValue(DurationValue, val) }
+
+// AddBytesValue adds a Value of type BytesValue.
+// The val parameter must be nil or of type []byte.
+func (b *RowBuilder) AddBytesValue(val interface{}) { b.AddValue(BytesValue, val) }
+
+// AddUUIDValue adds a Value of type UUIDValue.
+// The val parameter must be nil or of type UUID.
+func (b *RowBuilder) AddUUIDValue(val interface{}) { b.AddValue(UUIDValue, val) }
+
+// AddEnumValue adds a Value of type EnumValue.
+// The val parameter must be nil or of type string.
+func (b *RowBuilder) AddEnumValue(val interface{}) { b.AddValue(EnumValue, val) }
+
+// AddListValue adds a Value of type ListValue.
+// The val parameter must be nil or of type []*Value.
+func (b *RowBuilder) AddListValue(val interface{}) { b.AddValue(ListValue, val) }
+
 func (b *RowBuilder) build() *Row {
-	return &Row{b.values}
+	return &Row{Values: b.values}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/cvilsmeier/tdat"
@@ -9,7 +10,7 @@ import (
 )
 
 func convertToJSON(r io.Reader, w io.Writer, indent string) error {
-	model, err := tdat.ParseFromReader(r)
+	model, err := parse(r, tdat.ParseOptions{})
 	if err != nil {
 		return err
 	}
@@ -24,24 +25,7 @@ func convertToJSON(r io.Reader, w io.Writer, indent string) error {
 			jsRow := map[string]interface{}{}
 			for columnIndex, column := range table.Columns {
 				value := row.Values[columnIndex]
-				if value.Null {
-					jsRow[column.Name] = nil
-				} else {
-					switch value.Type {
-					case tdat.IntValue:
-						jsRow[column.Name] = value.AsInt
-					case tdat.FloatValue:
-						jsRow[column.Name] = value.AsFloat
-					case tdat.BoolValue:
-						jsRow[column.Name] = value.AsBool
-					case tdat.StringValue:
-						jsRow[column.Name] = value.AsString
-					case tdat.TimeValue:
-						jsRow[column.Name] = value.AsTime
-					default:
-						panic("invalid value type")
-					}
-				}
+				jsRow[column.Name] = toJSONValue(value)
 			}
 			jsTable = append(jsTable, jsRow)
 		}
@@ -56,8 +40,46 @@ func convertToJSON(r io.Reader, w io.Writer, indent string) error {
 	return err
 }
 
+// toJSONValue converts a value into a value for encoding/json.
+func toJSONValue(value *tdat.Value) interface{} {
+	if value.Null {
+		return nil
+	}
+	switch value.Type {
+	case tdat.IntValue:
+		return value.AsInt
+	case tdat.FloatValue:
+		return value.AsFloat
+	case tdat.BoolValue:
+		return value.AsBool
+	case tdat.StringValue, tdat.EnumValue:
+		return value.AsString
+	case tdat.TimeValue:
+		return value.AsTime
+	case tdat.DecimalValue:
+		// a JSON number keeps all digits
+		return json.Number(value.Decimal().String())
+	case tdat.DateValue:
+		return value.AsTime.Format("2006-01-02")
+	case tdat.DurationValue:
+		return tdat.FormatDuration(value.Duration())
+	case tdat.BytesValue:
+		// encoding/json writes []byte as base64
+		return value.Bytes()
+	case tdat.UUIDValue:
+		return value.UUID().String()
+	case tdat.ListValue:
+		list := []interface{}{}
+		for _, elem := range value.List() {
+			list = append(list, toJSONValue(elem))
+		}
+		return list
+	}
+	panic("invalid value type")
+}
+
 func convertToCSV(r io.Reader, w io.Writer) error {
-	model, err := tdat.ParseFromReader(r)
+	model, err := parse(r, tdat.ParseOptions{})
 	if err != nil {
 		return err
 	}
@@ -124,10 +146,27 @@ func convertTableToCSV(table *tdat.Table, csvWriter *csv.Writer) error {
 						cell = fmt.Sprintf("%f", value.AsFloat)
 					case tdat.BoolValue:
 						cell = fmt.Sprintf("%t", value.AsBool)
-					case tdat.StringValue:
+					case tdat.StringValue, tdat.EnumValue:
 						cell = value.AsString
 					case tdat.TimeValue:
 						cell = value.AsTime.Format("2006-01-02 15:04:05")
+					case tdat.DecimalValue:
+						cell = value.Decimal().String()
+					case tdat.DateValue:
+						cell = value.AsTime.Format("2006-01-02")
+					case tdat.DurationValue:
+						cell = tdat.FormatDuration(value.Duration())
+					case tdat.BytesValue:
+						cell = "0x" + hex.EncodeToString(value.Bytes())
+					case tdat.UUIDValue:
+						cell = value.UUID().String()
+					case tdat.ListValue:
+						// a list is written as a JSON array
+						data, err := json.Marshal(toJSONValue(value))
+						if err != nil {
+							return err
+						}
+						cell = string(data)
 					default:
 						panic("invalid value type")
 					}
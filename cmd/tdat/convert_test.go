@@ -142,3 +142,80 @@ func TestConvertFullModelToCSV(t *testing.T) {
 		"\n"
 	assert.EqStr(t, exp, act)
 }
+
+func TestConvertDecimal(t *testing.T) {
+	txt := "amounts\n" +
+		"|id:i  |amount:d\n" +
+		"|1     |12345678901234567890.10\n" +
+		"|2     |-0.005\n" +
+		"|3     |\n"
+	// to json
+	out := &bytes.Buffer{}
+	err := convertToJSON(bytes.NewBufferString(txt), out, "")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "{\"amounts\":[{\"amount\":12345678901234567890.10,\"id\":1},{\"amount\":-0.005,\"id\":2},{\"amount\":null,\"id\":3}]}\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+	// to csv
+	out = &bytes.Buffer{}
+	err = convertToCSV(bytes.NewBufferString(txt), out)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "amounts\nid;amount\n1;12345678901234567890.10\n2;-0.005\n3;\n\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+}
+
+func TestConvertDateAndDuration(t *testing.T) {
+	txt := "persons\n" +
+		"|born:D     |slept:P\n" +
+		"|1972-05-03 |PT7H30M\n" +
+		"|           |\n"
+	// to json
+	out := &bytes.Buffer{}
+	err := convertToJSON(bytes.NewBufferString(txt), out, "")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "{\"persons\":[{\"born\":\"1972-05-03\",\"slept\":\"PT7H30M\"},{\"born\":null,\"slept\":null}]}\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+	// to csv
+	out = &bytes.Buffer{}
+	err = convertToCSV(bytes.NewBufferString(txt), out)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "persons\nborn;slept\n1972-05-03;PT7H30M\n;\n\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+}
+
+func TestConvertBytesAndUUID(t *testing.T) {
+	txt := "files\n" +
+		"|id:U                                  |sum:B\n" +
+		"|123e4567-e89b-12d3-a456-426614174000  |0x6869\n" +
+		"|                                      |\n"
+	// to json
+	out := &bytes.Buffer{}
+	err := convertToJSON(bytes.NewBufferString(txt), out, "")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "{\"files\":[{\"id\":\"123e4567-e89b-12d3-a456-426614174000\",\"sum\":\"aGk=\"},{\"id\":null,\"sum\":null}]}\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+	// to csv
+	out = &bytes.Buffer{}
+	err = convertToCSV(bytes.NewBufferString(txt), out)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "files\nid;sum\n123e4567-e89b-12d3-a456-426614174000;0x6869\n;\n\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+}
+
+func TestConvertList(t *testing.T) {
+	txt := "persons\n" +
+		"|tags:[s]        |scores:[i]\n" +
+		"|[\"a\",\"b;c\"]   |[]\n" +
+		"|                |[1,2]\n"
+	// to json
+	out := &bytes.Buffer{}
+	err := convertToJSON(bytes.NewBufferString(txt), out, "")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "{\"persons\":[{\"scores\":[],\"tags\":[\"a\",\"b;c\"]},{\"scores\":[1,2],\"tags\":null}]}\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+	// to csv
+	out = &bytes.Buffer{}
+	err = convertToCSV(bytes.NewBufferString(txt), out)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "persons\ntags;scores\n\"[\"\"a\"\",\"\"b;c\"\"]\";[]\n;[1,2]\n\n"
+	assert.EqStr(t, exp, string(out.Bytes()))
+}
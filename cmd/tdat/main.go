@@ -11,6 +11,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/cvilsmeier/tdat"
+	"io"
 	"os"
 	"time"
 )
@@ -19,31 +20,40 @@ var cmdFlag = ""
 var inFlag = "-"
 var outFlag = "-"
 var indentFlag = ""
+var maxErrorsFlag = 0
+var lenientFlag = false
+var progressFlag = false
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "tdat - a tool for handling TDAT files\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Usage:\n")
 	fmt.Fprintf(os.Stderr, "\n")
-	fmt.Fprintf(os.Stderr, "tdat -cmd validate [-in <filename>] [-out <filename>]\n")
+	fmt.Fprintf(os.Stderr, "tdat -cmd validate [-in <filename>] [-out <filename>] [-maxerrors <n>] [-lenient] [-progress]\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "    Cmd validate parses and validates a tdat model. If the model\n")
 	fmt.Fprintf(os.Stderr, "    is valid, tdat will print nothing and exit with code 0.\n")
-	fmt.Fprintf(os.Stderr, "    If the model is not valid, tdat will print an error message to\n")
-	fmt.Fprintf(os.Stderr, "    stderr and exit with code 1.\n")
+	fmt.Fprintf(os.Stderr, "    If the model is not valid, tdat will print all errors (but\n")
+	fmt.Fprintf(os.Stderr, "    not more than maxerrors, if maxerrors > 0) to stderr, one\n")
+	fmt.Fprintf(os.Stderr, "    error per line, and exit with code 1.\n")
+	fmt.Fprintf(os.Stderr, "    Values must strictly conform to the TDAT grammar, unless lenient\n")
+	fmt.Fprintf(os.Stderr, "    is set. Use lenient for legacy files.\n")
 	fmt.Fprintf(os.Stderr, "\n")
-	fmt.Fprintf(os.Stderr, "tdat -cmd json [-in <filename>] [-out <filename>] [-indent <pattern>]\n")
+	fmt.Fprintf(os.Stderr, "tdat -cmd json [-in <filename>] [-out <filename>] [-indent <pattern>] [-progress]\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "    Cmd json parses and validates a tdat model and convert it to\n")
 	fmt.Fprintf(os.Stderr, "    JSON format. If indent is \"\" (the default), the JSON will be\n")
 	fmt.Fprintf(os.Stderr, "    written as one line. If indent is not empty, the JSON will\n")
 	fmt.Fprintf(os.Stderr, "    be multi-line, each line indented by the indent pattern.\n")
 	fmt.Fprintf(os.Stderr, "\n")
-	fmt.Fprintf(os.Stderr, "tdat -cmd csv [-in <filename>] [-out <filename>]\n")
+	fmt.Fprintf(os.Stderr, "tdat -cmd csv [-in <filename>] [-out <filename>] [-progress]\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "    Cmd csv parses and validates a tdat model and convert it to\n")
 	fmt.Fprintf(os.Stderr, "    CSV format.\n")
 	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "    All commands that parse a tdat model print the number of rows\n")
+	fmt.Fprintf(os.Stderr, "    and bytes parsed so far to stderr, if progress is set.\n")
+	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
@@ -54,6 +64,9 @@ func main() {
 	flag.StringVar(&inFlag, "in", inFlag, "read from the specified file. '-' means stdin.")
 	flag.StringVar(&outFlag, "out", outFlag, "write to the specified file. '-' means stdout.")
 	flag.StringVar(&indentFlag, "indent", indentFlag, "indentation of json output")
+	flag.BoolVar(&lenientFlag, "lenient", lenientFlag, "do not validate values strictly. Use for legacy files.")
+	flag.IntVar(&maxErrorsFlag, "maxerrors", maxErrorsFlag, "maximum number of errors reported by validate. 0 means no limit.")
+	flag.BoolVar(&progressFlag, "progress", progressFlag, "print progress to stderr while parsing.")
 	flag.Usage = usage
 	flag.Parse()
 	switch cmdFlag {
@@ -61,9 +74,11 @@ func main() {
 		sample()
 		os.Exit(0)
 	case "validate":
-		err := validate()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s", err)
+		errs := validate()
+		if len(errs) > 0 {
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+			}
 			os.Exit(1)
 		}
 		os.Exit(0)
@@ -109,25 +124,49 @@ func sample() {
 	fmt.Println(txt)
 }
 
-func validate() error {
+// validate returns all parse errors and the first validation error.
+func validate() []error {
 	r := os.Stdin
 	if inFlag != "-" {
 		f, err := os.Open(inFlag)
 		if err != nil {
-			return err
+			return []error{err}
 		}
 		defer f.Close()
 		r = f
 	}
-	model, err := tdat.ParseFromReader(r)
-	if err != nil {
-		return err
+	opts := tdat.ParseOptions{CollectErrors: true, MaxErrors: maxErrorsFlag, Strict: !lenientFlag}
+	model, err := parse(r, opts)
+	errs := []error{}
+	if errorList, ok := err.(tdat.ErrorList); ok {
+		for _, e := range errorList {
+			errs = append(errs, e)
+		}
+	} else if err != nil {
+		return []error{err}
+	}
+	if maxErrorsFlag > 0 && len(errs) >= maxErrorsFlag {
+		return errs
 	}
 	err = tdat.ValidateModel(model)
 	if err != nil {
-		return err
+		errs = append(errs, err)
 	}
-	return nil
+	return errs
+}
+
+// parse parses a model and prints progress, if the progress
+// flag is set.
+func parse(r io.Reader, opts tdat.ParseOptions) (*tdat.Model, error) {
+	if !progressFlag {
+		return opts.ParseFromReader(r)
+	}
+	opts.Progress = func(bytes int64, rows int) {
+		fmt.Fprintf(os.Stderr, "\rparsed %d rows, %d bytes", rows, bytes)
+	}
+	model, err := opts.ParseFromReader(r)
+	fmt.Fprintf(os.Stderr, "\n")
+	return model, err
 }
 
 func convert() error {
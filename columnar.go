@@ -0,0 +1,358 @@
+package tdat
+
+import (
+	"fmt"
+	"time"
+)
+
+// A table is either in row form or in columnar form. In row form, its
+// values are held in Rows. In columnar form, Rows is empty and the values
+// are held in one typed slice per column, with a bitmap for null values.
+// This needs much less memory and puts much less load on the garbage
+// collector than a *Value per cell.
+//
+// The parser creates tables in columnar form if ParseOptions.Columnar
+// is set. Tables are converted with ToColumns and ToRows. The methods
+// NumRows, IsNull, IntAt, FloatAt, BoolAt, StringAt, TimeAt, DecimalAt,
+// DurationAt, BytesAt, UUIDAt, ListAt, CustomAt and ValueAt read values in
+// both forms.
+
+// columnData holds the values of a column of a table in columnar form.
+// Only the slice for the column type is used, durations are held in
+// ints. A null value is stored as the zero value of its type and a set
+// bit in nulls.
+type columnData struct {
+	nulls   []uint64
+	ints    []int64
+	floats  []float64
+	bools   []bool
+	strings []string
+	times   []time.Time
+	decs    []Decimal
+	bytes   [][]byte
+	uuids   []UUID
+	lists   [][]*Value
+	customs []interface{}
+}
+
+// append appends a value to the column, at row index i.
+func (d *columnData) append(i int, v *Value) {
+	if i%64 == 0 {
+		d.nulls = append(d.nulls, 0)
+	}
+	if v.Null {
+		d.nulls[i/64] |= 1 << uint(i%64)
+	}
+	switch v.Type {
+	case IntValue, DurationValue:
+		d.ints = append(d.ints, v.AsInt)
+	case FloatValue:
+		d.floats = append(d.floats, v.AsFloat)
+	case BoolValue:
+		d.bools = append(d.bools, v.AsBool)
+	case StringValue, EnumValue:
+		d.strings = append(d.strings, v.AsString)
+	case TimeValue, DateValue:
+		d.times = append(d.times, v.AsTime)
+	case DecimalValue:
+		d.decs = append(d.decs, v.Decimal())
+	case BytesValue:
+		d.bytes = append(d.bytes, v.Bytes())
+	case UUIDValue:
+		d.uuids = append(d.uuids, v.UUID())
+	case ListValue:
+		d.lists = append(d.lists, v.List())
+	default:
+		d.customs = append(d.customs, v.AsCustom)
+	}
+}
+
+func (d *columnData) isNull(i int) bool {
+	return d.nulls[i/64]&(1<<uint(i%64)) != 0
+}
+
+// load sets v to the value at row index i.
+func (d *columnData) load(i int, valueType ValueType, v *Value) {
+	*v = Value{Type: valueType, Null: d.isNull(i)}
+	if v.Null {
+		return
+	}
+	switch valueType {
+	case IntValue, DurationValue:
+		v.AsInt = d.ints[i]
+	case FloatValue:
+		v.AsFloat = d.floats[i]
+	case BoolValue:
+		v.AsBool = d.bools[i]
+	case StringValue, EnumValue:
+		v.AsString = d.strings[i]
+	case TimeValue, DateValue:
+		v.AsTime = d.times[i]
+	case DecimalValue:
+		v.AsCustom = d.decs[i]
+	case BytesValue:
+		v.AsCustom = d.bytes[i]
+	case UUIDValue:
+		v.AsCustom = d.uuids[i]
+	case ListValue:
+		v.AsCustom = d.lists[i]
+	default:
+		v.AsCustom = d.customs[i]
+	}
+}
+
+// IsColumnar reports whether the table is in columnar form.
+func (t *Table) IsColumnar() bool {
+	return t.data != nil
+}
+
+// NumRows returns the number of rows of the table.
+func (t *Table) NumRows() int {
+	if t.data != nil {
+		return t.rowCount
+	}
+	return len(t.Rows)
+}
+
+// IsNull reports whether the value in row i and column j is null.
+func (t *Table) IsNull(i, j int) bool {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].isNull(i)
+	}
+	return t.Rows[i].Values[j].Null
+}
+
+// IntAt returns the value in row i and column j, which must be an IntValue
+// column. A null value is returned as 0.
+func (t *Table) IntAt(i, j int) int64 {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].ints[i]
+	}
+	return t.Rows[i].Values[j].AsInt
+}
+
+// FloatAt returns the value in row i and column j, which must be a FloatValue
+// column. A null value is returned as 0.
+func (t *Table) FloatAt(i, j int) float64 {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].floats[i]
+	}
+	return t.Rows[i].Values[j].AsFloat
+}
+
+// BoolAt returns the value in row i and column j, which must be a BoolValue
+// column. A null value is returned as false.
+func (t *Table) BoolAt(i, j int) bool {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].bools[i]
+	}
+	return t.Rows[i].Values[j].AsBool
+}
+
+// StringAt returns the value in row i and column j, which must be a StringValue
+// or EnumValue column. A null value is returned as "".
+func (t *Table) StringAt(i, j int) string {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].strings[i]
+	}
+	return t.Rows[i].Values[j].AsString
+}
+
+// TimeAt returns the value in row i and column j, which must be a TimeValue
+// or DateValue column. A null value is returned as the zero time.
+func (t *Table) TimeAt(i, j int) time.Time {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].times[i]
+	}
+	return t.Rows[i].Values[j].AsTime
+}
+
+// DecimalAt returns the value in row i and column j, which must be a
+// DecimalValue column. A null value is returned as the zero Decimal.
+func (t *Table) DecimalAt(i, j int) Decimal {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].decs[i]
+	}
+	return t.Rows[i].Values[j].Decimal()
+}
+
+// DurationAt returns the value in row i and column j, which must be a
+// DurationValue column. A null value is returned as 0.
+func (t *Table) DurationAt(i, j int) time.Duration {
+	if t.data != nil {
+		t.checkRow(i)
+		return time.Duration(t.data[j].ints[i])
+	}
+	return t.Rows[i].Values[j].Duration()
+}
+
+// BytesAt returns the value in row i and column j, which must be a
+// BytesValue column. A null value is returned as nil.
+func (t *Table) BytesAt(i, j int) []byte {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].bytes[i]
+	}
+	return t.Rows[i].Values[j].Bytes()
+}
+
+// UUIDAt returns the value in row i and column j, which must be a
+// UUIDValue column. A null value is returned as the zero UUID.
+func (t *Table) UUIDAt(i, j int) UUID {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].uuids[i]
+	}
+	return t.Rows[i].Values[j].UUID()
+}
+
+// ListAt returns the elements of the value in row i and column j, which
+// must be a ListValue column. A null value is returned as nil.
+func (t *Table) ListAt(i, j int) []*Value {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].lists[i]
+	}
+	return t.Rows[i].Values[j].List()
+}
+
+// CustomAt returns the value in row i and column j, which must be a
+// column of a type registered with RegisterType. A null value is
+// returned as nil.
+func (t *Table) CustomAt(i, j int) interface{} {
+	if t.data != nil {
+		t.checkRow(i)
+		return t.data[j].customs[i]
+	}
+	return t.Rows[i].Values[j].AsCustom
+}
+
+// ValueAt returns the value in row i and column j. In columnar form, the
+// returned value is a copy, changing it does not change the table.
+func (t *Table) ValueAt(i, j int) *Value {
+	if t.data != nil {
+		t.checkRow(i)
+		v := &Value{}
+		t.data[j].load(i, t.Columns[j].Type, v)
+		return v
+	}
+	return t.Rows[i].Values[j]
+}
+
+// checkRow panics if i is not a valid row index. All accessors call it
+// in columnar form, so that they fail alike. Without it, the null bitmap
+// and the typed slices may have room for more rows than the table has.
+func (t *Table) checkRow(i int) {
+	if i < 0 || i >= t.rowCount {
+		panic(fmt.Sprintf("row index %d out of range", i))
+	}
+}
+
+// ToColumns converts the table into columnar form. It fails, and
+// leaves the table unchanged, if a row does not match the columns.
+// If the table is in columnar form already, ToColumns does nothing.
+func (t *Table) ToColumns() error {
+	if t.data != nil {
+		return nil
+	}
+	for i, row := range t.Rows {
+		if len(row.Values) != len(t.Columns) {
+			return fmt.Errorf("row %d: expected %d values but got %d", i+1, len(t.Columns), len(row.Values))
+		}
+		for j, value := range row.Values {
+			if value.Type != t.Columns[j].Type {
+				return fmt.Errorf("row %d: column %q: expected value type '%c' but was '%c'", i+1, t.Columns[j].Name, t.Columns[j].Type, value.Type)
+			}
+		}
+	}
+	t.initColumns()
+	for _, row := range t.Rows {
+		t.appendRow(row)
+	}
+	t.Rows = []*Row{}
+	return nil
+}
+
+// ToRows converts the table into row form.
+// If the table is in row form already, ToRows does nothing.
+func (t *Table) ToRows() {
+	if t.data == nil {
+		return
+	}
+	rows := make([]*Row, t.rowCount)
+	t.forEachRow(func(i int, row *Row) bool {
+		values := make([]Value, len(row.Values))
+		r := &Row{Values: make([]*Value, len(row.Values)), Comments: row.Comments}
+		for j, value := range row.Values {
+			values[j] = *value
+			r.Values[j] = &values[j]
+		}
+		rows[i] = r
+		return true
+	})
+	t.Rows = rows
+	t.data = nil
+	t.rowCount = 0
+	t.rowComments = nil
+}
+
+// initColumns puts an empty table into columnar form.
+func (t *Table) initColumns() {
+	t.data = make([]*columnData, len(t.Columns))
+	for j := range t.data {
+		t.data[j] = &columnData{}
+	}
+	t.rowCount = 0
+	t.rowComments = nil
+}
+
+// appendRow appends the values and comments of a row to a table in
+// columnar form. The values must match the columns.
+func (t *Table) appendRow(row *Row) {
+	i := t.rowCount
+	for j, value := range row.Values {
+		t.data[j].append(i, value)
+	}
+	if len(row.Comments) > 0 {
+		if t.rowComments == nil {
+			t.rowComments = map[int][]string{}
+		}
+		t.rowComments[i] = row.Comments
+	}
+	t.rowCount++
+}
+
+// forEachRow calls fn for each row of the table, until fn returns false.
+// In columnar form, the row passed to fn is only valid during the call,
+// forEachRow reuses it for the next row.
+func (t *Table) forEachRow(fn func(i int, row *Row) bool) {
+	if t.data == nil {
+		for i, row := range t.Rows {
+			if !fn(i, row) {
+				return
+			}
+		}
+		return
+	}
+	values := make([]Value, len(t.data))
+	row := &Row{Values: make([]*Value, len(t.data))}
+	for j := range values {
+		row.Values[j] = &values[j]
+	}
+	for i := 0; i < t.rowCount; i++ {
+		for j, d := range t.data {
+			d.load(i, t.Columns[j].Type, &values[j])
+		}
+		row.Comments = t.rowComments[i]
+		if !fn(i, row) {
+			return
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestValueSize(t *testing.T) {
+	// every cell in row form pays for the size of a Value
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		assert.EqInt(t, 88, int(unsafe.Sizeof(Value{})))
+	}
+}
+
+func TestColumnar(t *testing.T) {
+	input := "# persons\n"
+	input += "persons\n"
+	input += "|id:i|rate:f|flag:b|name:s|born:t\n"
+	input += "|1|1.5|true|\"joe\"|2001-02-03T04:05:06\n"
+	input += "# sue\n"
+	input += "|||||\n"
+	input += "|3|-2|false|\"\"|2017-12-12T10:00:00.113\n"
+	input += "\n"
+	input += "empty\n"
+	model, err := ParseOptions{Columnar: true, Positions: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	persons := model.Tables[0]
+	assert.True(t, persons.IsColumnar())
+	assert.EqInt(t, 0, len(persons.Rows))
+	assert.EqInt(t, 3, persons.NumRows())
+	assert.EqStr(t, "2:1-7:39", persons.Span().String())
+	assert.True(t, persons.IntAt(0, 0) == 1)
+	assert.True(t, persons.FloatAt(2, 1) == -2)
+	assert.True(t, persons.BoolAt(0, 2))
+	assert.EqStr(t, "joe", persons.StringAt(0, 3))
+	assert.True(t, persons.TimeAt(2, 4).Equal(time.Date(2017, 12, 12, 10, 0, 0, 113000000, time.UTC)))
+	assert.True(t, !persons.IsNull(0, 0))
+	assert.True(t, persons.IsNull(1, 0))
+	assert.True(t, persons.IsNull(1, 4))
+	assert.True(t, !persons.IsNull(2, 3))
+	assert.True(t, persons.ValueAt(1, 3).Null)
+	assert.EqStr(t, "joe", persons.ValueAt(0, 3).AsString)
+	assert.True(t, model.Tables[1].IsColumnar())
+	assert.EqInt(t, 0, model.Tables[1].NumRows())
+	// rendering columnar and row form gives the same result
+	exp, err := RenderToString(mustParse(t, input), 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	act, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, exp, act)
+	// convert to rows and back
+	persons.ToRows()
+	assert.True(t, !persons.IsColumnar())
+	assert.EqInt(t, 3, len(persons.Rows))
+	assert.EqStr(t, "[sue]", fmt.Sprintf("%v", persons.Rows[1].Comments))
+	assert.True(t, persons.IntAt(2, 0) == 3)
+	assert.True(t, persons.IsNull(1, 2))
+	err = persons.ToColumns()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.True(t, persons.IsColumnar())
+	act, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, exp, act)
+	// null bitmap spans more than one word
+	input = "t\n|a:i|b:s\n"
+	for i := 0; i < 70; i++ {
+		input += fmt.Sprintf("|%d|\n", i)
+	}
+	model, err = ParseOptions{Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 70, model.Tables[0].NumRows())
+	assert.True(t, model.Tables[0].IntAt(69, 0) == 69)
+	assert.True(t, model.Tables[0].IsNull(69, 1))
+	assert.True(t, !model.Tables[0].IsNull(68, 0))
+}
+
+func TestColumnarRowOutOfRange(t *testing.T) {
+	builder := NewBuilder()
+	table := builder.AddTable("t")
+	types := []ValueType{IntValue, FloatValue, BoolValue, StringValue, TimeValue, DecimalValue, DurationValue, BytesValue, UUIDValue, ListValue}
+	row := table.AddRow()
+	for k, valueType := range types {
+		if valueType == ListValue {
+			table.AddListColumn(fmt.Sprintf("c%d", k), IntValue)
+		} else {
+			table.AddColumn(fmt.Sprintf("c%d", k), valueType)
+		}
+		row.AddValue(valueType, nil)
+	}
+	model, err := builder.BuildColumnar()
+	assert.Truef(t, err == nil, "err was %s", err)
+	tab := model.Tables[0]
+	accessors := []func(i int){
+		func(i int) { tab.IsNull(i, 0) },
+		func(i int) { tab.IntAt(i, 0) },
+		func(i int) { tab.FloatAt(i, 1) },
+		func(i int) { tab.BoolAt(i, 2) },
+		func(i int) { tab.StringAt(i, 3) },
+		func(i int) { tab.TimeAt(i, 4) },
+		func(i int) { tab.DecimalAt(i, 5) },
+		func(i int) { tab.DurationAt(i, 6) },
+		func(i int) { tab.BytesAt(i, 7) },
+		func(i int) { tab.UUIDAt(i, 8) },
+		func(i int) { tab.ListAt(i, 9) },
+		func(i int) { tab.CustomAt(i, 0) },
+		func(i int) { tab.ValueAt(i, 0) },
+	}
+	for _, accessor := range accessors {
+		for _, i := range []int{-1, 1} {
+			func() {
+				defer func() {
+					assert.EqStr(t, fmt.Sprintf("row index %d out of range", i), fmt.Sprintf("%v", recover()))
+				}()
+				accessor(i)
+			}()
+		}
+	}
+}
+
+func TestColumnarToColumns(t *testing.T) {
+	table := &Table{Name: "t", Columns: []*Column{{Name: "a", Type: IntValue}}, Rows: []*Row{
+		{Values: []*Value{{Type: IntValue, AsInt: 1}}},
+		{Values: []*Value{{Type: StringValue}}},
+	}}
+	err := table.ToColumns()
+	assert.EqStr(t, "row 2: column \"a\": expected value type 'i' but was 's'", err.Error())
+	assert.True(t, !table.IsColumnar())
+	table.Rows[1].Values = nil
+	err = table.ToColumns()
+	assert.EqStr(t, "row 2: expected 1 values but got 0", err.Error())
+	table.Rows = table.Rows[:1]
+	err = table.ToColumns()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 1, table.NumRows())
+	err = ValidateTable(table)
+	assert.Truef(t, err == nil, "err was %s", err)
+	table.Columns[0].Name = ""
+	err = ValidateTable(table)
+	assert.EqStr(t, "column \"\": name is empty", err.Error())
+}
+
+func TestBuildColumnar(t *testing.T) {
+	builder := NewBuilder()
+	table := builder.AddTable("products")
+	table.AddIntColumn("id")
+	table.AddStringColumn("name")
+	row := table.AddRow()
+	row.AddIntValue(int64(1))
+	row.AddStringValue("bottle")
+	row = table.AddRow()
+	row.AddIntValue(int64(2))
+	row.AddStringValue(nil)
+	model, err := builder.BuildColumnar()
+	assert.Truef(t, err == nil, "err was %s", err)
+	products := model.Tables[0]
+	assert.True(t, products.IsColumnar())
+	assert.EqInt(t, 2, products.NumRows())
+	assert.EqStr(t, "bottle", products.StringAt(0, 1))
+	assert.True(t, products.IsNull(1, 1))
+	// each call builds new columns
+	row = table.AddRow()
+	row.AddIntValue(int64(3))
+	row.AddStringValue("cup")
+	model, err = builder.BuildColumnar()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 3, model.Tables[0].NumRows())
+	assert.EqStr(t, "cup", model.Tables[0].StringAt(2, 1))
+	assert.EqInt(t, 2, products.NumRows())
+	assert.True(t, products.data[0] != model.Tables[0].data[0])
+	// rows and columns can be added in any order
+	builder = NewBuilder()
+	table = builder.AddTable("products")
+	table.AddIntColumn("id")
+	row1 := table.AddRow()
+	row2 := table.AddRow()
+	row1.AddIntValue(int64(1))
+	row2.AddIntValue(int64(2))
+	table.AddStringColumn("name")
+	row2.AddStringValue("cup")
+	row1.AddStringValue("bottle")
+	model, err = builder.BuildColumnar()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "bottle", model.Tables[0].StringAt(0, 1))
+	assert.EqInt(t, 2, int(model.Tables[0].IntAt(1, 0)))
+	// invalid
+	row = table.AddRow()
+	row.AddIntValue(int64(3))
+	_, err = builder.BuildColumnar()
+	assert.EqStr(t, "table \"products\": row 3: expected 2 values but got 1", err.Error())
+	_, err = builder.Build()
+	assert.EqStr(t, "table \"products\": row 3: expected 2 values but got 1", err.Error())
+	// errors are reported in the order of ValidateModel
+	builder = NewBuilder()
+	builder.AddTable("a").AddIntColumn("id")
+	table = builder.AddTable("b")
+	table.AddIntColumn("id")
+	table.AddRow().AddStringValue("x")
+	builder.AddTable("a")
+	_, err = builder.BuildColumnar()
+	assert.EqStr(t, "table \"b\": row 1, value 1: expected value type 'i' but was 's'", err.Error())
+	_, err = builder.Build()
+	assert.EqStr(t, "table \"b\": row 1, value 1: expected value type 'i' but was 's'", err.Error())
+}
+
+func BenchmarkParseColumnar(b *testing.B) {
+	rowCount := 10 * 1000
+	input := benchmarkInput(rowCount)
+	opts := ParseOptions{Columnar: true}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, err := opts.ParseBytes(input)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if m.Tables[0].NumRows() != rowCount {
+			b.Fatal("wrong row count")
+		}
+	}
+	allocs := testing.AllocsPerRun(1, func() {
+		opts.ParseBytes(input)
+	})
+	b.ReportMetric(allocs/float64(rowCount), "allocs/row")
+}
+
+func mustParse(t *testing.T, input string) *Model {
+	model, err := ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	return model
+}
@@ -0,0 +1,100 @@
+package tdat
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// A Decimal is an exact decimal number with arbitrary precision. Its
+// value is Unscaled * 10^-Scale, for example, 12.50 is represented as
+// Unscaled 1250 and Scale 2. The scale is kept when a decimal is parsed
+// and rendered, so 12.50 and 12.5 are equal in value but render
+// differently. A nil Unscaled is zero.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+// NewDecimal returns the decimal unscaled * 10^-scale.
+func NewDecimal(unscaled int64, scale int) Decimal {
+	return Decimal{big.NewInt(unscaled), scale}
+}
+
+// ParseDecimal parses a decimal number, like "-12.50". It accepts an
+// optional sign, one or more digits and an optional fraction, as
+// described in rfc.txt, section 3.6. Other than the grammar, it also
+// accepts leading zeros and a plus sign.
+func ParseDecimal(s string) (Decimal, error) {
+	digits := s
+	if digits != "" && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+	scale := 0
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		scale = len(digits) - i - 1
+		if i == 0 || scale == 0 {
+			return Decimal{}, fmt.Errorf("parsing %q: invalid syntax", s)
+		}
+		digits = digits[:i] + digits[i+1:]
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("parsing %q: invalid syntax", s)
+	}
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if s[0] == '-' {
+		unscaled.Neg(unscaled)
+	}
+	return Decimal{unscaled, scale}, nil
+}
+
+// String returns the decimal in the format of rfc.txt, section 3.6,
+// with Scale fraction digits.
+func (d Decimal) String() string {
+	if d.Unscaled == nil {
+		d.Unscaled = new(big.Int)
+	}
+	if d.Scale <= 0 {
+		s := d.Unscaled.String()
+		if d.Unscaled.Sign() != 0 {
+			s += strings.Repeat("0", -d.Scale)
+		}
+		return s
+	}
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	i := len(digits) - d.Scale
+	return sign + digits[:i] + "." + digits[i:]
+}
+
+// Rat returns the value of the decimal as a rational number.
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if d.Unscaled != nil {
+		r.SetInt(d.Unscaled)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(d.Scale))), nil)
+	if d.Scale > 0 {
+		return r.Quo(r, new(big.Rat).SetInt(scale))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(scale))
+}
+
+// Cmp compares the values of d and e, regardless of their scale. It
+// returns -1 if d < e, 0 if d == e and +1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.Rat().Cmp(e.Rat())
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
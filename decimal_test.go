@@ -0,0 +1,80 @@
+package tdat
+
+import (
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		input string
+		exp   string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"12.50", "12.50"},
+		{"-12.50", "-12.50"},
+		{"+7", "7"},
+		{"007.10", "7.10"},
+		{"0.001", "0.001"},
+		{"-0.000", "0.000"},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789"},
+		{"", "err parsing \"\": invalid syntax"},
+		{"-", "err parsing \"-\": invalid syntax"},
+		{".5", "err parsing \".5\": invalid syntax"},
+		{"5.", "err parsing \"5.\": invalid syntax"},
+		{"1e3", "err parsing \"1e3\": invalid syntax"},
+		{"1.2.3", "err parsing \"1.2.3\": invalid syntax"},
+	}
+	for _, testCase := range testCases {
+		d, err := ParseDecimal(testCase.input)
+		act := d.String()
+		if err != nil {
+			act = "err " + err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "input %q", testCase.input)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	assert.EqStr(t, "0", Decimal{}.String())
+	assert.EqStr(t, "0.00", Decimal{Scale: 2}.String())
+	assert.EqStr(t, "-0.05", NewDecimal(-5, 2).String())
+	assert.EqStr(t, "1200", NewDecimal(12, -2).String())
+	assert.EqStr(t, "0", NewDecimal(0, -2).String())
+	assert.EqStr(t, "1/8", NewDecimal(125, 3).Rat().String())
+	assert.EqStr(t, "1200/1", NewDecimal(12, -2).Rat().String())
+	assert.EqInt(t, 0, NewDecimal(125, 2).Cmp(NewDecimal(12500, 4)))
+	assert.EqInt(t, -1, NewDecimal(-1, 0).Cmp(Decimal{}))
+}
+
+func TestParseDecimalValues(t *testing.T) {
+	input := "amounts\n|amount:d\n|12.50\n|\n|-0.10\n"
+	model, err := ParseOptions{Strict: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	rows := model.Tables[0].Rows
+	assert.EqStr(t, "12.50", rows[0].Values[0].Decimal().String())
+	assert.True(t, rows[1].Values[0].Null)
+	// render exactly as parsed
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input+"\n", txt)
+	// strict grammar
+	_, err = ParseOptions{Strict: true}.ParseFromString("amounts\n|amount:d\n|012.50\n")
+	assert.EqStr(t, "line 3, pos 2: cannot parse as decimal: invalid syntax", err.Error())
+	_, err = ParseFromString("amounts\n|amount:d\n|12,50\n")
+	assert.EqStr(t, "line 3, pos 2: cannot parse as decimal: parsing \"12,50\": invalid syntax", err.Error())
+	// builder and columnar form
+	builder := NewBuilder()
+	table := builder.AddTable("amounts")
+	table.AddDecimalColumn("amount")
+	table.AddRow().AddDecimalValue(NewDecimal(1250, 2))
+	table.AddRow().AddDecimalValue(nil)
+	model, err = builder.BuildColumnar()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "12.50", model.Tables[0].DecimalAt(0, 0).String())
+	assert.True(t, model.Tables[0].IsNull(1, 0))
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "amounts\n|amount:d\n|12.50\n|\n\n", txt)
+}
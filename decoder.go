@@ -0,0 +1,88 @@
+package tdat
+
+import (
+	"io"
+)
+
+// A Decoder reads tables and rows from an input stream, one at a time.
+// Unlike ParseFromReader, a Decoder does not build a Model, so it can
+// process large inputs in constant memory.
+//
+// A typical read loop looks like this:
+//
+//	dec := tdat.NewDecoder(r)
+//	for {
+//	    table, err := dec.NextTable()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    ...
+//	    for {
+//	        row, err := dec.NextRow()
+//	        if err == io.EOF {
+//	            break
+//	        }
+//	        ...
+//	    }
+//	}
+type Decoder struct {
+	p     *parser
+	event parserEvent
+	err   error
+}
+
+// NewDecoder creates a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{newParser(newLexer(r), ParseOptions{}), noEvent, nil}
+}
+
+// NextTable advances to the next table and returns it. Rows of the
+// current table that have not been read by NextRow are skipped.
+// The returned table contains name and columns, its Rows are
+// always empty.
+// At the end of the input, NextTable returns nil and io.EOF.
+func (d *Decoder) NextTable() (*Table, error) {
+	for {
+		event, err := d.peek()
+		if err != nil {
+			return nil, err
+		}
+		d.event = noEvent
+		switch event {
+		case tableEvent:
+			return d.p.table, nil
+		case endEvent:
+			d.event = endEvent
+			return nil, io.EOF
+		}
+	}
+}
+
+// NextRow returns the next row of the current table.
+// If the current table has no more rows, NextRow returns nil and io.EOF.
+// The values of the returned row correspond to the columns of the table
+// that was last returned by NextTable.
+func (d *Decoder) NextRow() (*Row, error) {
+	event, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	if event != rowEvent {
+		return nil, io.EOF
+	}
+	d.event = noEvent
+	return d.p.row, nil
+}
+
+// peek returns the next parser event without consuming it.
+// Errors are sticky, once the parser has failed, peek will
+// always return that error.
+func (d *Decoder) peek() (parserEvent, error) {
+	if d.err != nil {
+		return noEvent, d.err
+	}
+	if d.event == noEvent {
+		d.event, d.err = d.p.next()
+	}
+	return d.event, d.err
+}
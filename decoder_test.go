@@ -0,0 +1,98 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	input := "\n"
+	input += "persons\n"
+	input += "|id:i   |name:s\n"
+	input += "|1      |\"joe\"\n"
+	input += "|2      |\n"
+	input += "\n"
+	input += "empty\n"
+	input += "\n"
+	input += "cars\n"
+	input += "|id:i|brand:s\n"
+	input += "|1|\"bmw\"\n"
+	dec := NewDecoder(strings.NewReader(input))
+	act := ""
+	for {
+		table, err := dec.NextTable()
+		if err == io.EOF {
+			break
+		}
+		assert.Truef(t, err == nil, "err was %s", err)
+		act += fmt.Sprintf("table %s(%d columns, %d rows)\n", table.Name, len(table.Columns), len(table.Rows))
+		for {
+			row, err := dec.NextRow()
+			if err == io.EOF {
+				break
+			}
+			assert.Truef(t, err == nil, "err was %s", err)
+			act += fmt.Sprintf("row(%d values)\n", len(row.Values))
+		}
+	}
+	exp := "table persons(2 columns, 0 rows)\n"
+	exp += "row(2 values)\n"
+	exp += "row(2 values)\n"
+	exp += "table empty(0 columns, 0 rows)\n"
+	exp += "table cars(2 columns, 0 rows)\n"
+	exp += "row(2 values)\n"
+	assert.EqStr(t, exp, act)
+	// eof is sticky
+	_, err := dec.NextTable()
+	assert.True(t, err == io.EOF)
+	_, err = dec.NextRow()
+	assert.True(t, err == io.EOF)
+}
+
+func TestDecoderSkipRows(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i\n"
+	input += "|1\n"
+	input += "|2\n"
+	input += "cars\n"
+	input += "|id:i\n"
+	input += "|3\n"
+	dec := NewDecoder(strings.NewReader(input))
+	table, err := dec.NextTable()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons", table.Name)
+	// skip all rows of persons
+	table, err = dec.NextTable()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "cars", table.Name)
+	row, err := dec.NextRow()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 3, int(row.Values[0].AsInt))
+	_, err = dec.NextRow()
+	assert.True(t, err == io.EOF)
+	_, err = dec.NextTable()
+	assert.True(t, err == io.EOF)
+}
+
+func TestDecoderError(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i\n"
+	input += "|1\n"
+	input += "|x\n"
+	input += "|3\n"
+	dec := NewDecoder(strings.NewReader(input))
+	_, err := dec.NextTable()
+	assert.Truef(t, err == nil, "err was %s", err)
+	row, err := dec.NextRow()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 1, int(row.Values[0].AsInt))
+	_, err = dec.NextRow()
+	assert.True(t, err != nil)
+	assert.EqStr(t, "line 4, pos 2: cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax", err.Error())
+	// errors are sticky
+	_, err = dec.NextTable()
+	assert.True(t, err != nil)
+}
@@ -18,5 +18,21 @@ Parsing sample.tdat:
 		tables, err := tdat.ParseFile("sample.tdat")
 	}
 
+Values:
+
+A Value holds ints, floats, bools, strings and times in the fields
+AsInt, AsFloat, AsBool, AsString and AsTime. Decimals, bytes, UUIDs and
+lists are held in AsCustom, durations in AsInt, and are read with the
+methods Value.Decimal, Value.Duration, Value.Bytes, Value.UUID and
+Value.List:
+
+	for _, row := range table.Rows {
+		price := row.Values[1].Decimal()
+		timeout := row.Values[2].Duration()
+	}
+
+For large tables, see ParseOptions.Columnar and the accessors of Table,
+like Table.DecimalAt, which need no Value per cell.
+
 */
 package tdat
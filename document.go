@@ -0,0 +1,507 @@
+package tdat
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Document is a TDAT text that keeps its original formatting.
+// Unlike a Model, a Document remembers whitespace, padding, quoting,
+// blank lines and comments. It can be edited with SetCell, InsertRow,
+// DeleteRow and DropColumn. Edits touch only the lines and cells
+// they change, all other text is written back as it was read.
+type Document struct {
+	// ExactTimes formats time values set by SetCell and InsertRow
+	// with their UTC offset and with up to nanosecond precision,
+	// see RenderOptions.
+	ExactTimes bool
+
+	lines   []*docLine
+	tables  []*docTable
+	newline string
+}
+
+// A docLine is a line of a document. Name, comment and blank
+// lines have no cells, their text is the whole line. For header and
+// row lines, text is the text before the first cell.
+// Eol is the line ending, it is empty for the last line if the
+// input does not end with a newline.
+type docLine struct {
+	text    string
+	cells   []*docCell
+	eol     string
+	comment bool
+}
+
+// A docCell is a cell of a header or row line. Lead is the separator
+// and the whitespace before the cell text, trail is the whitespace
+// after the cell text.
+type docCell struct {
+	lead  string
+	text  string
+	trail string
+}
+
+type docTable struct {
+	name    string
+	columns []*Column
+	header  *docLine
+	rows    []*docLine
+	lists   []bool
+}
+
+// ParseDocumentFromString is like ParseDocumentFromReader but reads
+// input from a string.
+func ParseDocumentFromString(input string) (*Document, error) {
+	return ParseOptions{}.ParseDocumentFromString(input)
+}
+
+// ParseDocumentFromReader parses a Document from an io.Reader.
+// It returns any error that occurs while parsing the input.
+func ParseDocumentFromReader(reader io.Reader) (*Document, error) {
+	return ParseOptions{}.ParseDocumentFromReader(reader)
+}
+
+// ParseDocumentFromString is like the package-level function
+// ParseDocumentFromString but uses the options in o. A Document cannot
+// hold erroneous lines, so if o collects errors, the errors are returned
+// but no Document.
+// A Document holds all tables and columns in row form, the options
+// Tables, Columns and Columnar are ignored.
+func (o ParseOptions) ParseDocumentFromString(input string) (*Document, error) {
+	o.Tables, o.Columns, o.Columnar = nil, nil, false
+	model, err := o.ParseFromString(input)
+	if err != nil {
+		return nil, err
+	}
+	lex := newLexer(strings.NewReader(input))
+	lex.strict = o.Strict
+	d := &Document{newline: "\n"}
+	err = d.scan(input, lex)
+	if err != nil {
+		return nil, err
+	}
+	err = d.attach(model)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// attach sets the names and columns of the scanned tables from the
+// parsed model. It fails if the scanner and the parser found
+// different tables.
+func (d *Document) attach(model *Model) error {
+	if len(d.tables) != len(model.Tables) {
+		return fmt.Errorf("internal: document has %d tables, model %d", len(d.tables), len(model.Tables))
+	}
+	for i, table := range d.tables {
+		table.name = model.Tables[i].Name
+		table.columns = append([]*Column{}, model.Tables[i].Columns...)
+	}
+	return nil
+}
+
+// ParseDocumentFromReader is like the package-level function
+// ParseDocumentFromReader but uses the options in o.
+func (o ParseOptions) ParseDocumentFromReader(reader io.Reader) (*Document, error) {
+	if o.MaxBytes > 0 {
+		// one more byte, so that the parser sees that the input is too large
+		reader = io.LimitReader(reader, o.MaxBytes+1)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return o.ParseDocumentFromString(string(data))
+}
+
+// scan splits the input into lines and cells. The input must be
+// valid, scan does not check the grammar. Lines are recognized like the
+// parser does: The first line with cells after a name line is the
+// header of the table, all further lines with cells are rows.
+// Lists are read in the list columns of rows only, like the parser does.
+func (d *Document) scan(input string, lex *lexer) error {
+	lineStart := 0
+	var seps []int
+	first, last := tokenType(0), tokenType(0)
+	for {
+		lex.lists = last == separatorToken && d.isListColumn(len(seps)-1)
+		tok, err := lex.next()
+		if err != nil {
+			return err
+		}
+		offset := int(tok.offset)
+		lineEnd := -1
+		switch tok.ttype {
+		case separatorToken:
+			seps = append(seps, offset)
+		case newlineToken:
+			lineEnd = offset + 1
+		case commentToken:
+			lineEnd = len(input)
+			if i := strings.IndexByte(input[offset:], '\n'); i >= 0 {
+				lineEnd = offset + i + 1
+			}
+		case eofToken:
+			lineEnd = offset
+		}
+		if first == 0 {
+			first = tok.ttype
+		}
+		last = tok.ttype
+		if lineEnd < 0 {
+			continue
+		}
+		if lineEnd > lineStart {
+			d.addLine(input[lineStart:lineEnd], lineStart, seps, first)
+		}
+		if tok.ttype == eofToken {
+			return nil
+		}
+		lineStart = lineEnd
+		seps = nil
+		first = 0
+	}
+}
+
+// addLine adds a line that starts at byte offset start.
+// The offsets of the separators in the line are given in seps.
+// First is the type of the first token of the line.
+func (d *Document) addLine(raw string, start int, seps []int, first tokenType) {
+	line := &docLine{}
+	switch {
+	case strings.HasSuffix(raw, "\r\n"):
+		line.eol = "\r\n"
+	case strings.HasSuffix(raw, "\n"):
+		line.eol = "\n"
+	}
+	if len(d.lines) == 0 && line.eol != "" {
+		d.newline = line.eol
+	}
+	raw = raw[:len(raw)-len(line.eol)]
+	d.lines = append(d.lines, line)
+	switch first {
+	case textToken:
+		line.text = raw
+		d.tables = append(d.tables, &docTable{})
+		return
+	case commentToken:
+		line.text = raw
+		line.comment = true
+		return
+	case separatorToken:
+	default:
+		line.text = raw
+		return
+	}
+	// a header or row line
+	line.text = raw[:seps[0]-start]
+	for i, sep := range seps {
+		end := len(raw)
+		if i+1 < len(seps) {
+			end = seps[i+1] - start
+		}
+		line.cells = append(line.cells, newDocCell(raw[sep-start:end]))
+	}
+	table := d.tables[len(d.tables)-1]
+	if table.header == nil && len(table.rows) == 0 {
+		table.header = line
+		table.lists = listColumns([]byte(raw[seps[0]-start+1:]))
+	} else {
+		table.rows = append(table.rows, line)
+	}
+}
+
+// isListColumn reports whether column col of the current table,
+// which is the last table scanned so far, is a list column.
+func (d *Document) isListColumn(col int) bool {
+	if len(d.tables) == 0 {
+		return false
+	}
+	table := d.tables[len(d.tables)-1]
+	return table.header != nil && col < len(table.lists) && table.lists[col]
+}
+
+// newDocCell creates a cell from its raw text, which starts with
+// the separator.
+func newDocCell(raw string) *docCell {
+	const ws = " \t\r"
+	text := strings.TrimLeft(raw[1:], ws)
+	lead := raw[:len(raw)-len(text)]
+	trimmed := strings.TrimRight(text, ws)
+	return &docCell{lead, trimmed, text[len(trimmed):]}
+}
+
+// String returns the text of the document.
+func (d *Document) String() string {
+	var sb strings.Builder
+	d.WriteTo(&sb)
+	return sb.String()
+}
+
+// WriteTo writes the text of the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, line := range d.lines {
+		c, err := io.WriteString(w, line.String())
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (line *docLine) String() string {
+	if len(line.cells) == 0 {
+		return line.text + line.eol
+	}
+	var sb strings.Builder
+	sb.WriteString(line.text)
+	for _, cell := range line.cells {
+		sb.WriteString(cell.lead)
+		sb.WriteString(cell.text)
+		sb.WriteString(cell.trail)
+	}
+	sb.WriteString(line.eol)
+	return sb.String()
+}
+
+// Model parses the text of the document and returns it as a Model.
+func (d *Document) Model() (*Model, error) {
+	return ParseFromString(d.String())
+}
+
+// SetCell sets the value of a cell. The rowIndex is zero-based.
+// If the column is padded, the cell is padded to the width of the
+// column, so that the following cells stay in place, if possible.
+func (d *Document) SetCell(tableName string, rowIndex int, columnName string, value *Value) error {
+	table, err := d.table(tableName)
+	if err != nil {
+		return err
+	}
+	if rowIndex < 0 || rowIndex >= len(table.rows) {
+		return fmt.Errorf("table %q: row index %d out of range", tableName, rowIndex)
+	}
+	colIndex, err := table.columnIndex(columnName)
+	if err != nil {
+		return err
+	}
+	text, err := table.formatCell(colIndex, value, d.ExactTimes)
+	if err != nil {
+		return err
+	}
+	width := table.width(colIndex)
+	cell := table.rows[rowIndex].cells[colIndex]
+	cell.text = text
+	if width > 0 {
+		cell.trail = padding(width - utf8.RuneCountInString(text))
+	}
+	return nil
+}
+
+// InsertRow inserts a row before the row at rowIndex. If rowIndex equals
+// the number of rows, the row is appended. The values must match the
+// columns of the table. The new row starts like the row (or header)
+// before it, its cells are padded to the width of their columns.
+func (d *Document) InsertRow(tableName string, rowIndex int, values ...*Value) error {
+	table, err := d.table(tableName)
+	if err != nil {
+		return err
+	}
+	if rowIndex < 0 || rowIndex > len(table.rows) {
+		return fmt.Errorf("table %q: row index %d out of range", tableName, rowIndex)
+	}
+	if table.header == nil {
+		return fmt.Errorf("table %q: no columns", tableName)
+	}
+	err = validateRow(table.columns, rowIndex, &Row{Values: values})
+	if err != nil {
+		return fmt.Errorf("table %q: %s", tableName, err)
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i], err = table.formatCell(i, value, d.ExactTimes)
+		if err != nil {
+			return err
+		}
+	}
+	// format like the previous line
+	ref := table.header
+	if rowIndex > 0 {
+		ref = table.rows[rowIndex-1]
+	}
+	line := &docLine{text: ref.text, eol: ref.eol}
+	for i, text := range texts {
+		cell := &docCell{ref.cells[i].lead, text, ""}
+		if width := table.width(i); width > 0 {
+			cell.trail = padding(width - utf8.RuneCountInString(text))
+		}
+		line.cells = append(line.cells, cell)
+	}
+	// insert the line
+	var lineIndex int
+	if rowIndex < len(table.rows) {
+		// insert before the comments of the next row
+		lineIndex = d.lineIndex(table.rows[rowIndex])
+		for lineIndex > 0 && d.lines[lineIndex-1].comment {
+			lineIndex--
+		}
+	} else {
+		lineIndex = d.lineIndex(ref) + 1
+		if ref.eol == "" {
+			ref.eol = d.newline
+		}
+	}
+	d.lines = append(d.lines, nil)
+	copy(d.lines[lineIndex+1:], d.lines[lineIndex:])
+	d.lines[lineIndex] = line
+	table.rows = append(table.rows, nil)
+	copy(table.rows[rowIndex+1:], table.rows[rowIndex:])
+	table.rows[rowIndex] = line
+	return nil
+}
+
+// DeleteRow deletes the row at rowIndex, together with the comment
+// lines before it.
+func (d *Document) DeleteRow(tableName string, rowIndex int) error {
+	table, err := d.table(tableName)
+	if err != nil {
+		return err
+	}
+	if rowIndex < 0 || rowIndex >= len(table.rows) {
+		return fmt.Errorf("table %q: row index %d out of range", tableName, rowIndex)
+	}
+	end := d.lineIndex(table.rows[rowIndex]) + 1
+	start := end - 1
+	for start > 0 && d.lines[start-1].comment {
+		start--
+	}
+	if d.lines[end-1].eol == "" && start > 0 {
+		d.lines[start-1].eol = ""
+	}
+	d.lines = append(d.lines[:start], d.lines[end:]...)
+	table.rows = append(table.rows[:rowIndex], table.rows[rowIndex+1:]...)
+	return nil
+}
+
+// DropColumn removes a column from the header and from all rows of
+// a table. If the last column is dropped, the header and all rows
+// are removed.
+func (d *Document) DropColumn(tableName string, columnName string) error {
+	table, err := d.table(tableName)
+	if err != nil {
+		return err
+	}
+	colIndex, err := table.columnIndex(columnName)
+	if err != nil {
+		return err
+	}
+	if len(table.columns) == 1 {
+		for len(table.rows) > 0 {
+			d.DeleteRow(tableName, len(table.rows)-1)
+		}
+		d.deleteLine(table.header)
+		table.header = nil
+		table.columns = nil
+		return nil
+	}
+	table.columns = append(table.columns[:colIndex], table.columns[colIndex+1:]...)
+	lines := append([]*docLine{table.header}, table.rows...)
+	for _, line := range lines {
+		dropped := line.cells[colIndex]
+		line.cells = append(line.cells[:colIndex], line.cells[colIndex+1:]...)
+		if colIndex == len(line.cells) {
+			// the new last cell gets the trail of the former last cell
+			line.cells[colIndex-1].trail = dropped.trail
+		}
+	}
+	return nil
+}
+
+func (d *Document) deleteLine(line *docLine) {
+	i := d.lineIndex(line)
+	if line.eol == "" && i > 0 {
+		d.lines[i-1].eol = ""
+	}
+	d.lines = append(d.lines[:i], d.lines[i+1:]...)
+}
+
+func (d *Document) lineIndex(line *docLine) int {
+	for i, l := range d.lines {
+		if l == line {
+			return i
+		}
+	}
+	panic("line not found")
+}
+
+func (d *Document) table(name string) (*docTable, error) {
+	for _, table := range d.tables {
+		if table.name == name {
+			return table, nil
+		}
+	}
+	return nil, fmt.Errorf("table %q not found", name)
+}
+
+func (t *docTable) columnIndex(name string) (int, error) {
+	for i, column := range t.columns {
+		if column.Name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("table %q: column %q not found", t.name, name)
+}
+
+// width returns the width of the column at colIndex, that is the
+// widest text and padding of its padded cells in the header and the
+// rows. It returns 0 if no cell of the column is padded. Cells that
+// are wider than the column have no padding, they do not count.
+func (t *docTable) width(colIndex int) int {
+	width := 0
+	measure := func(line *docLine) {
+		cell := line.cells[colIndex]
+		if cell.trail != "" {
+			w := utf8.RuneCountInString(cell.text) + utf8.RuneCountInString(cell.trail)
+			if w > width {
+				width = w
+			}
+		}
+	}
+	measure(t.header)
+	for _, row := range t.rows {
+		measure(row)
+	}
+	return width
+}
+
+// formatCell formats a value for the column at colIndex.
+func (t *docTable) formatCell(colIndex int, value *Value, exactTimes bool) (string, error) {
+	column := t.columns[colIndex]
+	if value == nil {
+		return "", fmt.Errorf("table %q, column %q: value is nil", t.name, column.Name)
+	}
+	if value.Type != column.Type {
+		return "", fmt.Errorf("table %q, column %q: expected value type '%c' but was '%c'", t.name, column.Name, column.Type, value.Type)
+	}
+	if value.Type == StringValue && !utf8.ValidString(value.AsString) {
+		return "", fmt.Errorf("table %q, column %q: invalid UTF-8", t.name, column.Name)
+	}
+	if err := validateValue(column, value); err != nil {
+		return "", fmt.Errorf("table %q: %s", t.name, err)
+	}
+	return formatValue(value, exactTimes), nil
+}
+
+// padding returns n spaces, or none if n <= 0.
+func padding(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(" ", n)
+}
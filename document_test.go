@@ -0,0 +1,226 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"\n\n",
+		"persons",
+		"persons\n|id:i|name:s\n|1|\"joe\"",
+		"  persons  \r\n\r\n  |id:i   |name:s  \r\n|  1  |  \" joe | \"  \r\n\r\n",
+		"# comment\npersons\n#\n|id:i\n# row comment\n|1\n\n# the end",
+		"\ufeffpersons\n|id:i\n|1\n",
+		"a\n\nb\n|x:f\n|1.5\n\nc\n",
+	}
+	for _, input := range inputs {
+		doc, err := ParseDocumentFromString(input)
+		assert.Truef(t, err == nil, "err was %s", err)
+		assert.EqStr(t, input, doc.String())
+	}
+	// invalid input
+	_, err := ParseDocumentFromString("persons\n|id:i\n|x")
+	assert.EqStr(t, "line 3, pos 2: cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax", err.Error())
+	// a reader is not read beyond MaxBytes, the timeout reader fails on a second read
+	reader := iotest.TimeoutReader(strings.NewReader("persons\n|id:i|name:s\n|1|\"joe\"\n"))
+	_, err = ParseOptions{MaxBytes: 20}.ParseDocumentFromReader(reader)
+	assert.EqStr(t, "line 2, pos 13: limit exceeded: MaxBytes is 20", err.Error())
+}
+
+func TestDocumentAttach(t *testing.T) {
+	d, err := ParseDocumentFromString("persons\n|id:i\n|1\n\nplaces\n|id:i\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	model, err := ParseFromString("persons\n|id:i\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = d.attach(model)
+	assert.EqStr(t, "internal: document has 2 tables, model 1", err.Error())
+}
+
+func TestDocumentSetCell(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i |name:s   |born:t\n"
+	input += "|1    |\"joe\"    |\n"
+	input += "|2    |\"sue\"    |2001-02-03T04:05:06\n"
+	doc, err := ParseDocumentFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.SetCell("persons", 0, "name", &Value{Type: StringValue, AsString: "jack"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.SetCell("persons", 1, "id", &Value{Type: IntValue, AsInt: 123456})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.SetCell("persons", 1, "born", &Value{Type: TimeValue, Null: true})
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "persons\n"
+	exp += "|id:i |name:s   |born:t\n"
+	exp += "|1    |\"jack\"   |\n"
+	exp += "|123456|\"sue\"    |\n"
+	assert.EqStr(t, exp, doc.String())
+	// errors
+	err = doc.SetCell("cars", 0, "id", &Value{Type: IntValue})
+	assert.EqStr(t, "table \"cars\" not found", err.Error())
+	err = doc.SetCell("persons", 2, "id", &Value{Type: IntValue})
+	assert.EqStr(t, "table \"persons\": row index 2 out of range", err.Error())
+	err = doc.SetCell("persons", 0, "age", &Value{Type: IntValue})
+	assert.EqStr(t, "table \"persons\": column \"age\" not found", err.Error())
+	err = doc.SetCell("persons", 0, "id", &Value{Type: StringValue})
+	assert.EqStr(t, "table \"persons\", column \"id\": expected value type 'i' but was 's'", err.Error())
+	err = doc.SetCell("persons", 0, "id", nil)
+	assert.EqStr(t, "table \"persons\", column \"id\": value is nil", err.Error())
+	// rows after a wide cell keep the width of the column
+	err = doc.InsertRow("persons", 2, &Value{Type: IntValue, AsInt: 3}, &Value{Type: StringValue, AsString: "al"}, &Value{Type: TimeValue, Null: true})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.SetCell("persons", 1, "name", &Value{Type: StringValue, AsString: "susanne"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.SetCell("persons", 1, "name", &Value{Type: StringValue, AsString: "sue"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "persons\n"
+	exp += "|id:i |name:s   |born:t\n"
+	exp += "|1    |\"jack\"   |\n"
+	exp += "|123456|\"sue\"    |\n"
+	exp += "|3    |\"al\"     |\n"
+	assert.EqStr(t, exp, doc.String())
+	err = doc.DeleteRow("persons", 2)
+	assert.Truef(t, err == nil, "err was %s", err)
+	// exact times
+	born := time.Date(2001, 2, 3, 4, 5, 6, 7000, time.FixedZone("", 3600))
+	err = doc.SetCell("persons", 0, "born", &Value{Type: TimeValue, AsTime: born})
+	assert.Truef(t, err == nil, "err was %s", err)
+	doc.ExactTimes = true
+	err = doc.SetCell("persons", 1, "born", &Value{Type: TimeValue, AsTime: born})
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "persons\n"
+	exp += "|id:i |name:s   |born:t\n"
+	exp += "|1    |\"jack\"   |2001-02-03T03:05:06\n"
+	exp += "|123456|\"sue\"    |2001-02-03T04:05:06.000007+01:00\n"
+	assert.EqStr(t, exp, doc.String())
+}
+
+func TestDocumentInsertRow(t *testing.T) {
+	input := "persons\r\n"
+	input += "|id:i |name:s\r\n"
+	input += "|1    |\"joe\"\r\n"
+	input += "# sue\r\n"
+	input += "|2    |\"sue\"\r\n"
+	input += "\r\n"
+	input += "cars\r\n"
+	input += "| id:i   | built:t\r\n"
+	input += "\r\n"
+	input += "# the end"
+	doc, err := ParseDocumentFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.InsertRow("persons", 0, &Value{Type: IntValue, AsInt: 0}, &Value{Type: StringValue, AsString: "bob"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.InsertRow("persons", 2, &Value{Type: IntValue, AsInt: 12}, &Value{Type: StringValue, Null: true})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.InsertRow("persons", 4, &Value{Type: IntValue, AsInt: 3}, &Value{Type: StringValue, AsString: "al"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	built := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	err = doc.InsertRow("cars", 0, &Value{Type: IntValue, AsInt: 7}, &Value{Type: TimeValue, AsTime: built})
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "persons\r\n"
+	exp += "|id:i |name:s\r\n"
+	exp += "|0    |\"bob\"\r\n"
+	exp += "|1    |\"joe\"\r\n"
+	exp += "|12   |\r\n"
+	exp += "# sue\r\n"
+	exp += "|2    |\"sue\"\r\n"
+	exp += "|3    |\"al\"\r\n"
+	exp += "\r\n"
+	exp += "cars\r\n"
+	exp += "| id:i   | built:t\r\n"
+	exp += "| 7      | 2001-02-03T04:05:06\r\n"
+	exp += "\r\n"
+	exp += "# the end"
+	assert.EqStr(t, exp, doc.String())
+	model, err := doc.Model()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 5, len(model.Tables[0].Rows))
+	assert.EqStr(t, "[sue]", fmt.Sprintf("%v", model.Tables[0].Rows[3].Comments))
+	// errors
+	err = doc.InsertRow("persons", 6, &Value{Type: IntValue}, &Value{Type: StringValue})
+	assert.EqStr(t, "table \"persons\": row index 6 out of range", err.Error())
+	err = doc.InsertRow("persons", 0, &Value{Type: IntValue})
+	assert.EqStr(t, "table \"persons\": row 1: expected 2 values but got 1", err.Error())
+	err = doc.InsertRow("persons", 0, &Value{Type: IntValue}, nil)
+	assert.EqStr(t, "table \"persons\": row 1, value 2: value is nil", err.Error())
+	// append to a table without rows and a last line without newline
+	doc, err = ParseDocumentFromString("cars\n|id:i")
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.InsertRow("cars", 0, &Value{Type: IntValue, AsInt: 1})
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "cars\n|id:i\n|1", doc.String())
+	// a table without columns
+	doc, err = ParseDocumentFromString("cars\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.InsertRow("cars", 0)
+	assert.EqStr(t, "table \"cars\": no columns", err.Error())
+}
+
+func TestDocumentDeleteRow(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i\n"
+	input += "|1\n"
+	input += "# two\n"
+	input += "|2\n"
+	input += "|3"
+	doc, err := ParseDocumentFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.DeleteRow("persons", 2)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons\n|id:i\n|1\n# two\n|2", doc.String())
+	err = doc.DeleteRow("persons", 1)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons\n|id:i\n|1", doc.String())
+	err = doc.DeleteRow("persons", 1)
+	assert.EqStr(t, "table \"persons\": row index 1 out of range", err.Error())
+}
+
+func TestDocumentDropColumn(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i   |name:s   |age:i\n"
+	input += "|1      |\"joe\"    |33\n"
+	input += "|2      |\"sue\"    |\n"
+	input += "\n"
+	input += "cars\n"
+	input += "|id:i\n"
+	input += "|1\n"
+	input += "\n"
+	doc, err := ParseDocumentFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.DropColumn("persons", "name")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "persons\n"
+	exp += "|id:i   |age:i\n"
+	exp += "|1      |33\n"
+	exp += "|2      |\n"
+	exp += "\n"
+	exp += "cars\n"
+	exp += "|id:i\n"
+	exp += "|1\n"
+	exp += "\n"
+	assert.EqStr(t, exp, doc.String())
+	err = doc.DropColumn("persons", "age")
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = doc.DropColumn("cars", "id")
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "persons\n"
+	exp += "|id:i\n"
+	exp += "|1\n"
+	exp += "|2\n"
+	exp += "\n"
+	exp += "cars\n"
+	exp += "\n"
+	assert.EqStr(t, exp, doc.String())
+	err = doc.DropColumn("cars", "id")
+	assert.EqStr(t, "table \"cars\": column \"id\" not found", err.Error())
+	// the document can be edited after dropping
+	err = doc.SetCell("persons", 1, "id", &Value{Type: IntValue, AsInt: 22})
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "|22\n", doc.lines[3].String())
+}
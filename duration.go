@@ -0,0 +1,135 @@
+package tdat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses an ISO 8601 duration, like "PT1H30M" or
+// "-P2DT0.5S", as described in rfc.txt, section 3.8. A day is 24
+// hours. Years, months and weeks are not supported, since they have
+// no fixed length. Other than the grammar, it also accepts a plus
+// sign and lowercase designators.
+func ParseDuration(s string) (time.Duration, error) {
+	text := strings.ToUpper(s)
+	neg := false
+	if text != "" && (text[0] == '-' || text[0] == '+') {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	if text == "" || text[0] != 'P' {
+		return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+	}
+	text = text[1:]
+	// the designators in the order they must appear, 'D' before 'T'
+	// and 'H', 'M' and 'S' after it
+	const designators = "DHMS"
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total uint64
+	next, count := 0, 0
+	inTime := false
+	for text != "" {
+		if text[0] == 'T' {
+			if inTime || len(text) == 1 {
+				return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+			}
+			inTime = true
+			next = 1
+			text = text[1:]
+			continue
+		}
+		i := strings.IndexAny(text, "DTHMS")
+		if i < 0 {
+			return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+		}
+		k := strings.IndexByte(designators[next:], text[i])
+		if k < 0 || (next+k > 0) != inTime {
+			return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+		}
+		k += next
+		number, frac := text[:i], ""
+		text = text[i+1:]
+		next = k + 1
+		count++
+		if j := strings.IndexByte(number, '.'); j >= 0 && designators[k] == 'S' {
+			number, frac = number[:j], number[j+1:]
+			if len(frac) > 9 || !isDigits(frac) {
+				return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+			}
+		}
+		if !isDigits(number) {
+			return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+		}
+		unit := uint64(units[k])
+		x, err := strconv.ParseUint(number, 10, 64)
+		if err != nil || x > (1<<63-total)/unit {
+			return 0, fmt.Errorf("parsing %q: out of range", s)
+		}
+		total += x * unit
+		if frac != "" {
+			nsec, _ := strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+			if nsec > 1<<63-total {
+				return 0, fmt.Errorf("parsing %q: out of range", s)
+			}
+			total += nsec
+		}
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("parsing %q: invalid syntax", s)
+	}
+	if neg {
+		return -time.Duration(total), nil
+	}
+	if total == 1<<63 {
+		return 0, fmt.Errorf("parsing %q: out of range", s)
+	}
+	return time.Duration(total), nil
+}
+
+// FormatDuration formats a duration as described in rfc.txt,
+// section 3.8, with days, hours, minutes and seconds. Components
+// that are zero are left out. A zero duration is formatted as "PT0S".
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	sign := ""
+	x := uint64(d)
+	if d < 0 {
+		sign = "-"
+		x = -x
+	}
+	b := &strings.Builder{}
+	b.WriteString(sign)
+	b.WriteString("P")
+	day := uint64(24 * time.Hour)
+	if x >= day {
+		fmt.Fprintf(b, "%dD", x/day)
+		x %= day
+	}
+	if x == 0 {
+		return b.String()
+	}
+	b.WriteString("T")
+	if h := x / uint64(time.Hour); h > 0 {
+		fmt.Fprintf(b, "%dH", h)
+	}
+	if m := x / uint64(time.Minute) % 60; m > 0 {
+		fmt.Fprintf(b, "%dM", m)
+	}
+	sec, nsec := x/uint64(time.Second)%60, x%uint64(time.Second)
+	if nsec > 0 {
+		frac := strings.TrimRight(fmt.Sprintf("%09d", nsec), "0")
+		fmt.Fprintf(b, "%d.%sS", sec, frac)
+	} else if sec > 0 {
+		fmt.Fprintf(b, "%dS", sec)
+	}
+	return b.String()
+}
+
+// isDigits reports whether s is not empty and consists of digits only.
+func isDigits(s string) bool {
+	return s != "" && strings.TrimLeft(s, "0123456789") == ""
+}
@@ -0,0 +1,106 @@
+package tdat
+
+import (
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		input string
+		exp   string
+	}{
+		{"PT0S", "0s"},
+		{"P0D", "0s"},
+		{"PT1H30M", "1h30m0s"},
+		{"PT90M", "1h30m0s"},
+		{"PT1H2M3.5S", "1h2m3.5s"},
+		{"PT0.000000001S", "1ns"},
+		{"P1DT1H", "25h0m0s"},
+		{"-P1D", "-24h0m0s"},
+		{"+pt1m", "1m0s"},
+		{"PT2562047H47M16.854775807S", "2562047h47m16.854775807s"},
+		{"-PT2562047H47M16.854775808S", "-2562047h47m16.854775808s"},
+		{"PT2562047H47M16.854775808S", "err parsing \"PT2562047H47M16.854775808S\": out of range"},
+		{"P106752D", "err parsing \"P106752D\": out of range"},
+		{"PT99999999999999999999S", "err parsing \"PT99999999999999999999S\": out of range"},
+		{"", "err parsing \"\": invalid syntax"},
+		{"P", "err parsing \"P\": invalid syntax"},
+		{"PT", "err parsing \"PT\": invalid syntax"},
+		{"P1DT", "err parsing \"P1DT\": invalid syntax"},
+		{"P1Y", "err parsing \"P1Y\": invalid syntax"},
+		{"P1W", "err parsing \"P1W\": invalid syntax"},
+		{"P1H", "err parsing \"P1H\": invalid syntax"},
+		{"PT1D", "err parsing \"PT1D\": invalid syntax"},
+		{"PT1M1H", "err parsing \"PT1M1H\": invalid syntax"},
+		{"PT1H1H", "err parsing \"PT1H1H\": invalid syntax"},
+		{"PT1.5M", "err parsing \"PT1.5M\": invalid syntax"},
+		{"PT1.S", "err parsing \"PT1.S\": invalid syntax"},
+		{"PT1.0000000001S", "err parsing \"PT1.0000000001S\": invalid syntax"},
+		{"PTH", "err parsing \"PTH\": invalid syntax"},
+		{"P1DT1HT1M", "err parsing \"P1DT1HT1M\": invalid syntax"},
+	}
+	for _, testCase := range testCases {
+		d, err := ParseDuration(testCase.input)
+		act := d.String()
+		if err != nil {
+			act = "err " + err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "input %q", testCase.input)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	testCases := []struct {
+		d   time.Duration
+		exp string
+	}{
+		{0, "PT0S"},
+		{time.Nanosecond, "PT0.000000001S"},
+		{1500 * time.Millisecond, "PT1.5S"},
+		{90 * time.Minute, "PT1H30M"},
+		{-24 * time.Hour, "-P1D"},
+		{49*time.Hour + 1*time.Second, "P2DT1H1S"},
+		{-1 << 63, "-P106751DT23H47M16.854775808S"},
+	}
+	for _, testCase := range testCases {
+		act := FormatDuration(testCase.d)
+		assert.EqStrf(t, testCase.exp, act, "duration %s", testCase.d)
+		d, err := ParseDuration(act)
+		assert.Truef(t, err == nil, "err was %s", err)
+		assert.Truef(t, d == testCase.d, "round trip %s", act)
+		assert.Truef(t, isDuration([]byte(act)), "isDuration %s", act)
+	}
+}
+
+func TestParseDateAndDurationValues(t *testing.T) {
+	input := "persons\n|born:D|slept:P\n|1972-05-03|PT7H30M\n||\n"
+	model, err := ParseOptions{Strict: true, Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	persons := model.Tables[0]
+	assert.True(t, persons.TimeAt(0, 0).Equal(time.Date(1972, 5, 3, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, persons.DurationAt(0, 1) == 7*time.Hour+30*time.Minute)
+	assert.True(t, persons.IsNull(1, 1))
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input+"\n", txt)
+	// builder
+	builder := NewBuilder()
+	table := builder.AddTable("persons")
+	table.AddDateColumn("born")
+	table.AddDurationColumn("slept")
+	row := table.AddRow()
+	row.AddDateValue(time.Date(1972, 5, 3, 0, 0, 0, 0, time.Local))
+	row.AddDurationValue(-time.Minute)
+	model, err = builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons\n|born:D|slept:P\n|1972-05-03|-PT1M\n\n", txt)
+	row = table.AddRow()
+	row.AddDateValue(time.Date(1972, 5, 3, 12, 0, 0, 0, time.UTC))
+	row.AddDurationValue(nil)
+	_, err = builder.Build()
+	assert.EqStr(t, "table \"persons\": row 2, value 1: date has a time of day", err.Error())
+}
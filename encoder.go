@@ -0,0 +1,101 @@
+package tdat
+
+import (
+	"fmt"
+	"io"
+)
+
+// An Encoder writes tables and rows to an output stream, one at a time.
+// Unlike RenderToWriter, an Encoder does not need a complete Model, so it
+// can write rows as they are produced, for example from a database cursor.
+//
+// An Encoder uses the same formatting rules as RenderToWriter. Tables and
+// rows are validated as they are written, the way ValidateModel does.
+// A table or row that is invalid is not written, instead an error is
+// returned.
+type Encoder struct {
+	r          *renderer
+	tableNames map[string]bool
+	table      string
+	columns    []*Column
+	rowCount   int
+	closed     bool
+}
+
+// NewEncoder creates a new Encoder that writes to w.
+func NewEncoder(w io.Writer, opts RenderOptions) *Encoder {
+	r := &renderer{w: w, colWidth: opts.ColWidth, exactTimes: opts.ExactTimes}
+	return &Encoder{r: r, tableNames: map[string]bool{}}
+}
+
+// BeginTable ends the current table (if any) and writes the name
+// and columns of a new table. Subsequent calls to WriteRow write
+// rows to the new table.
+func (e *Encoder) BeginTable(name string, columns []*Column) error {
+	if e.closed {
+		return fmt.Errorf("encoder is closed")
+	}
+	if e.r.err != nil {
+		return e.r.err
+	}
+	err := ValidateName(name)
+	if err != nil {
+		return fmt.Errorf("table %q: %s", name, err)
+	}
+	if e.tableNames[name] {
+		return fmt.Errorf("duplicate table %q", name)
+	}
+	err = validateColumns(columns)
+	if err != nil {
+		return fmt.Errorf("table %q: %s", name, err)
+	}
+	e.endTable()
+	e.tableNames[name] = true
+	e.table = name
+	e.columns = columns
+	e.rowCount = 0
+	e.r.printf("%s\n", name)
+	e.r.renderColumns(columns)
+	return e.r.err
+}
+
+// WriteRow writes a row of values to the current table. The values
+// must match the columns given in BeginTable.
+func (e *Encoder) WriteRow(values ...*Value) error {
+	if e.closed {
+		return fmt.Errorf("encoder is closed")
+	}
+	if e.r.err != nil {
+		return e.r.err
+	}
+	if e.table == "" {
+		return fmt.Errorf("no table")
+	}
+	row := &Row{Values: values}
+	err := validateRow(e.columns, e.rowCount, row)
+	if err != nil {
+		return fmt.Errorf("table %q: %s", e.table, err)
+	}
+	e.rowCount++
+	e.r.renderRow(row)
+	return e.r.err
+}
+
+// Close ends the current table (if any). It does not close the
+// underlying writer. After Close, the Encoder cannot be used anymore.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return fmt.Errorf("encoder is closed")
+	}
+	e.closed = true
+	e.endTable()
+	return e.r.err
+}
+
+func (e *Encoder) endTable() {
+	if e.table != "" {
+		e.r.printf("\n")
+	}
+	e.table = ""
+	e.columns = nil
+}
@@ -0,0 +1,89 @@
+package tdat
+
+import (
+	"bytes"
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, RenderOptions{ColWidth: 8})
+	err := enc.BeginTable("persons", []*Column{{Name: "id", Type: IntValue}, {Name: "name", Type: StringValue}})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.WriteRow(&Value{Type: IntValue, AsInt: 1}, &Value{Type: StringValue, AsString: "joe"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.WriteRow(&Value{Type: IntValue, AsInt: 2}, &Value{Type: StringValue, Null: true})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.BeginTable("empty", nil)
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.Close()
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "persons\n"
+	exp += "|id:i    |name:s\n"
+	exp += "|1       |\"joe\"\n"
+	exp += "|2       |\n"
+	exp += "\n"
+	exp += "empty\n"
+	exp += "\n"
+	assert.EqStr(t, exp, buf.String())
+	// encoder output must equal renderer output
+	model := &Model{
+		Tables: []*Table{
+			{
+				Name:    "persons",
+				Columns: []*Column{{Name: "id", Type: IntValue}, {Name: "name", Type: StringValue}},
+				Rows: []*Row{
+					{Values: []*Value{{Type: IntValue, AsInt: 1}, {Type: StringValue, AsString: "joe"}}},
+					{Values: []*Value{{Type: IntValue, AsInt: 2}, {Type: StringValue, Null: true}}},
+				},
+			},
+			{Name: "empty"},
+		},
+	}
+	txt, err := RenderToString(model, 8)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, txt, buf.String())
+}
+
+func TestEncoderValidation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, RenderOptions{})
+	err := enc.WriteRow(&Value{Type: IntValue, AsInt: 1})
+	assert.EqStr(t, "no table", err.Error())
+	err = enc.BeginTable("", nil)
+	assert.EqStr(t, "table \"\": name is empty", err.Error())
+	err = enc.BeginTable("persons", []*Column{{Name: "id", Type: IntValue}, {Name: "id", Type: StringValue}})
+	assert.EqStr(t, "table \"persons\": duplicate column \"id\"", err.Error())
+	err = enc.BeginTable("persons", []*Column{{Name: "id", Type: IntValue}})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.WriteRow()
+	assert.EqStr(t, "table \"persons\": row 1: expected 1 values but got 0", err.Error())
+	err = enc.WriteRow(&Value{Type: BoolValue, AsBool: true})
+	assert.EqStr(t, "table \"persons\": row 1, value 1: expected value type 'i' but was 'b'", err.Error())
+	err = enc.WriteRow(&Value{Type: IntValue, AsInt: 1})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.BeginTable("persons", nil)
+	assert.EqStr(t, "duplicate table \"persons\"", err.Error())
+	err = enc.Close()
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.Close()
+	assert.EqStr(t, "encoder is closed", err.Error())
+	// invalid tables and rows are not written
+	assert.EqStr(t, "persons\n|id:i\n|1\n\n", buf.String())
+}
+
+func TestEncoderInvalidUTF8(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, RenderOptions{})
+	err := enc.BeginTable("persons", []*Column{{Name: "id", Type: IntValue}, {Name: "name", Type: StringValue}})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.WriteRow(&Value{Type: IntValue, AsInt: 1}, &Value{Type: StringValue, AsString: "\xff"})
+	assert.EqStr(t, "table \"persons\": row 1, value 2: string \"\\xff\" contains invalid UTF-8", err.Error())
+	// the encoder can still be used
+	err = enc.WriteRow(&Value{Type: IntValue, AsInt: 2}, &Value{Type: StringValue, AsString: "joe"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	err = enc.Close()
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons\n|id:i|name:s\n|2|\"joe\"\n\n", buf.String())
+}
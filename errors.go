@@ -0,0 +1,73 @@
+package tdat
+
+import (
+	"fmt"
+)
+
+// A ParseError is returned if the input cannot be parsed.
+// It locates the error in the input and, if available, names
+// the table, column and row where the error occurred.
+type ParseError struct {
+	// Line is the line number where the error occurred, starting at 1.
+	Line int
+
+	// Pos is the character position within the line, starting at 1.
+	Pos int
+
+	// TableName is the name of the table that was being parsed,
+	// or empty if the error occurred before the first table.
+	TableName string
+
+	// ColumnName is the name of the column of the cell that was being
+	// parsed, or empty if the error did not occur within a cell.
+	ColumnName string
+
+	// RowIndex is the zero-based index of the data row that was being
+	// parsed, or -1 if the error did not occur within a data row.
+	RowIndex int
+
+	// Cause describes what went wrong.
+	Cause error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, pos %d: %s", e.Line, e.Pos, e.Cause)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// A LimitExceededError is the cause of a ParseError if the input
+// exceeds a limit that was set in ParseOptions.
+type LimitExceededError struct {
+	// Limit is the name of the limit, for example "MaxRowsPerTable".
+	Limit string
+
+	// Max is the value of the limit.
+	Max int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("limit exceeded: %s is %d", e.Limit, e.Max)
+}
+
+func isLimitError(e *ParseError) bool {
+	_, ok := e.Cause.(*LimitExceededError)
+	return ok
+}
+
+// An ErrorList is a list of ParseErrors. It is returned by parsers
+// that collect errors, see ParseOptions.CollectErrors.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
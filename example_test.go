@@ -91,3 +91,26 @@ func ExampleRenderToString() {
 	// |id:i      |name:s
 	// |1         |"bottle"
 }
+
+func ExampleTable_ValueSpan() {
+	input := `
+products
+|id:i  |price:f
+|1     |2.50
+|2     |-1.00
+`
+	opts := tdat.ParseOptions{Positions: true}
+	model, err := opts.ParseFromString(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	products := model.Tables[0]
+	for i, row := range products.Rows {
+		price := row.Values[1]
+		if price.AsFloat < 0 {
+			fmt.Printf("products.tdat:%s: negative price\n", products.ValueSpan(i, 1).Start)
+		}
+	}
+	// Output:
+	// products.tdat:5:9: negative price
+}
@@ -0,0 +1,263 @@
+package tdat
+
+// The functions in this file check value texts against the
+// grammar in rfc.txt, section 3. They are used by the parser
+// in strict mode.
+
+// isInteger reports whether text is an integer as defined
+// in rfc.txt, section 3.1:
+//
+//	integer = [ minus ] digits [ exp ]
+func isInteger(text []byte) bool {
+	i := skipMinus(text, 0)
+	i, ok := scanDigits(text, i)
+	if !ok {
+		return false
+	}
+	i, ok = scanExp(text, i)
+	return ok && i == len(text)
+}
+
+// isFloat reports whether text is a floating point value as
+// defined in rfc.txt, section 3.2:
+//
+//	float = [ minus ] digits [ frac ] [ exp ]
+func isFloat(text []byte) bool {
+	i := skipMinus(text, 0)
+	i, ok := scanDigits(text, i)
+	if !ok {
+		return false
+	}
+	i, ok = scanFrac(text, i)
+	if !ok {
+		return false
+	}
+	i, ok = scanExp(text, i)
+	return ok && i == len(text)
+}
+
+// isDecimal reports whether text is a decimal value as
+// defined in rfc.txt, section 3.6:
+//
+//	decimal = [ minus ] digits [ frac ]
+func isDecimal(text []byte) bool {
+	i := skipMinus(text, 0)
+	i, ok := scanDigits(text, i)
+	if !ok {
+		return false
+	}
+	i, ok = scanFrac(text, i)
+	return ok && i == len(text)
+}
+
+// isBoolean reports whether text is a boolean as defined in
+// rfc.txt, section 3.3:
+//
+//	boolean = "true" / "false"
+func isBoolean(text []byte) bool {
+	return string(text) == "true" || string(text) == "false"
+}
+
+// isTime reports whether text is a time value as defined in
+// rfc.txt, section 3.5:
+//
+//	time = date t time
+//	date = year "-" month "-" day
+//	time = hour ":" minute ":" second [frac]
+//
+// It checks the syntax only, not the ranges of month, day, etc.
+func isTime(text []byte) bool {
+	const pattern = "0000-00-00T00:00:00"
+	if len(text) < len(pattern) || !matchPattern(text[:len(pattern)], pattern) {
+		return false
+	}
+	i, ok := scanFrac(text, len(pattern))
+	return ok && i == len(text)
+}
+
+// isDate reports whether text is a date value as defined in
+// rfc.txt, section 3.7:
+//
+//	date = year "-" month "-" day
+//
+// It checks the syntax only, not the ranges of month and day.
+func isDate(text []byte) bool {
+	return matchPattern(text, "0000-00-00")
+}
+
+// isDuration reports whether text is a duration value as defined
+// in rfc.txt, section 3.8:
+//
+//	duration = [ minus ] "P" ( days [ time ] / time )
+//	days     = 1*DIGIT "D"
+//	time     = "T" ( hours [ minutes ] [ seconds ] /
+//	                 minutes [ seconds ] / seconds )
+//	hours    = 1*DIGIT "H"
+//	minutes  = 1*DIGIT "M"
+//	seconds  = 1*DIGIT [ frac ] "S"
+func isDuration(text []byte) bool {
+	i := skipMinus(text, 0)
+	if i >= len(text) || text[i] != 'P' {
+		return false
+	}
+	i, days := scanComponent(text, i+1, 'D')
+	if i == len(text) {
+		return days
+	}
+	if text[i] != 'T' {
+		return false
+	}
+	i++
+	n := 0
+	for _, designator := range []byte("HMS") {
+		var ok bool
+		i, ok = scanComponent(text, i, designator)
+		if ok {
+			n++
+		}
+	}
+	return n > 0 && i == len(text)
+}
+
+// isBytes reports whether text is a bytes value as defined in
+// rfc.txt, section 3.9:
+//
+//	bytes = "0x" *( 2HEXDIG )
+func isBytes(text []byte) bool {
+	if len(text) < 2 || text[0] != '0' || text[1] != 'x' || len(text)%2 != 0 {
+		return false
+	}
+	for _, c := range text[2:] {
+		if !isHexDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID reports whether text is a UUID value as defined in
+// rfc.txt, section 3.10:
+//
+//	uuid = 8HEXDIG "-" 4HEXDIG "-" 4HEXDIG "-" 4HEXDIG "-" 12HEXDIG
+func isUUID(text []byte) bool {
+	return matchPattern(text, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+}
+
+// scanComponent scans a duration component with the given
+// designator at index i. Only seconds may have a fraction.
+// It returns the index after the component, and false if there
+// is no such component at i.
+func scanComponent(text []byte, i int, designator byte) (int, bool) {
+	j := skipDigits(text, i)
+	if j == i {
+		return i, false
+	}
+	if designator == 'S' {
+		var ok bool
+		if j, ok = scanFrac(text, j); !ok {
+			return i, false
+		}
+	}
+	if j >= len(text) || text[j] != designator {
+		return i, false
+	}
+	return j + 1, true
+}
+
+// matchPattern reports whether text matches pattern, where
+// a '0' in pattern matches any digit and an 'x' matches any
+// hex digit.
+func matchPattern(text []byte, pattern string) bool {
+	if len(text) != len(pattern) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '0':
+			if !isDigit(text[i]) {
+				return false
+			}
+		case 'x':
+			if !isHexDigit(text[i]) {
+				return false
+			}
+		default:
+			if text[i] != pattern[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// skipMinus skips an optional minus sign at index i.
+func skipMinus(text []byte, i int) int {
+	if i < len(text) && text[i] == '-' {
+		return i + 1
+	}
+	return i
+}
+
+// scanDigits scans the digits rule at index i:
+//
+//	digits = zero / ( digit1-9 *DIGIT )
+//
+// It returns the index after the digits, and false if there
+// are no digits at i.
+func scanDigits(text []byte, i int) (int, bool) {
+	if i >= len(text) || !isDigit(text[i]) {
+		return i, false
+	}
+	if text[i] == '0' {
+		return i + 1, true
+	}
+	return skipDigits(text, i), true
+}
+
+// scanFrac scans the optional frac rule at index i:
+//
+//	frac = decimal-point 1*DIGIT
+//
+// It returns the index after the fraction, and false if there
+// is a decimal point that is not followed by a digit.
+func scanFrac(text []byte, i int) (int, bool) {
+	if i >= len(text) || text[i] != '.' {
+		return i, true
+	}
+	j := skipDigits(text, i+1)
+	return j, j > i+1
+}
+
+// scanExp scans the optional exp rule at index i:
+//
+//	exp = e [ minus / plus ] 1*DIGIT
+//
+// It returns the index after the exponent, and false if there
+// is an 'e' or 'E' that is not followed by a valid exponent.
+func scanExp(text []byte, i int) (int, bool) {
+	if i >= len(text) || (text[i] != 'e' && text[i] != 'E') {
+		return i, true
+	}
+	i++
+	if i < len(text) && (text[i] == '-' || text[i] == '+') {
+		i++
+	}
+	j := skipDigits(text, i)
+	return j, j > i
+}
+
+// skipDigits skips zero or more digits at index i.
+func skipDigits(text []byte, i int) int {
+	for i < len(text) && isDigit(text[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -1,10 +1,12 @@
 package tdat
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"strconv"
-	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type tokenType byte
@@ -13,6 +15,7 @@ const (
 	textToken tokenType = iota + 1
 	separatorToken
 	newlineToken
+	commentToken
 	eofToken
 )
 
@@ -24,49 +27,116 @@ func (tt tokenType) String() string {
 		return "separator"
 	case newlineToken:
 		return "newline"
+	case commentToken:
+		return "comment"
 	case eofToken:
 		return "eof"
 	}
 	panic("unknown token type")
 }
 
-// A token is a lexeme.
+// A token is a lexeme. It starts at line and pos, end is the position
+// after its last rune. Offset is the byte offset of the token
+// in the input. For text tokens, quoted tells whether the text was
+// enclosed in double quotes. A text that starts with '[' is a list,
+// its text includes the brackets, see readList. For comment tokens,
+// text is the comment without the leading '#'.
+// The text is not copied, it points into a buffer of the lexer.
 type token struct {
-	line  int
-	pos   int
-	ttype tokenType
-	text  string
+	line   int
+	pos    int
+	end    Position
+	offset int64
+	ttype  tokenType
+	text   []byte
+	quoted bool
 }
 
 func (t *token) String() string {
 	return fmt.Sprintf("%d:%d %s(%s)", t.line, t.pos, t.ttype, t.text)
 }
 
-// The lexer scans input for tokens. It iterates over the
-// runes in the reader processes each rune one-by-one.
+// The lexer scans input for tokens. It reads the input into a byte
+// buffer and decodes the runes in the buffer one-by-one.
 // Initially, the lexer points to the first rune.
-// At the end of the reader (if the reader has no more runes to read),
-// the lexer stops.
+// At the end of the input, the lexer stops.
+// Unquoted text is sliced out of the buffer, quoted text is decoded
+// into a scratch buffer, so scanning a token does not allocate.
+// If the lexer reads from a reader, it refills the buffer when it has
+// consumed it. Refilling discards the bytes before the current rune,
+// or before mark, if mark is >= 0.
+// In strict mode, the lexer rejects quoted text that does not conform
+// to rfc.txt, section 3.4.
+// If maxBytes or maxStringLen are > 0, the lexer fails if the input
+// has more bytes, or a text has more runes, respectively.
+// The flag bol is true if no token has been scanned on the current
+// line yet, it is used for detecting comment lines.
+// The flag lists is true if the next token is the value of a list
+// column. It is set by the parser, which knows the columns. Otherwise,
+// a text that starts with '[' is read like any other text.
+// The offset is the byte offset of the current rune, base is the
+// byte offset of buf[0] and i is the index of the byte after the
+// current rune.
 type lexer struct {
-	reader io.RuneReader
-	r      rune
-	err    error
-	line   int
-	pos    int
+	reader       io.Reader
+	readErr      error
+	buf          []byte
+	i            int
+	base         int64
+	mark         int
+	r            rune
+	err          error
+	line         int
+	pos          int
+	offset       int64
+	bol          bool
+	lists        bool
+	strict       bool
+	maxBytes     int64
+	maxStringLen int
+	scratch      []byte
+	runeCount    int
+	tok          token
+}
+
+const lexerBufferSize = 64 * 1024
+
+// newLexer creates a lexer that reads its input from a reader.
+func newLexer(reader io.Reader) *lexer {
+	return initLexer(&lexer{reader: reader, buf: make([]byte, 0, lexerBufferSize)})
+}
+
+// newBytesLexer creates a lexer that scans a byte slice. The lexer
+// neither copies nor modifies the input.
+func newBytesLexer(input []byte) *lexer {
+	return initLexer(&lexer{buf: input})
 }
 
-func newLexer(reader io.RuneReader) *lexer {
-	l := &lexer{
-		reader,
-		-1,
-		nil,
-		1,
-		0,
+// newSegmentLexer creates a lexer that scans the part of input that
+// starts at byte offset start, in line line. Positions and offsets
+// are relative to the beginning of input.
+func newSegmentLexer(input []byte, start int, line int) *lexer {
+	return initLexer(&lexer{buf: input, i: start, line: line})
+}
+
+func initLexer(l *lexer) *lexer {
+	l.r = -1
+	if l.line == 0 {
+		l.line = 1
 	}
+	l.bol = true
+	l.mark = -1
 	l.read()
+	// ignore a byte order mark, see rfc.txt, section 4.1
+	if l.r == byteOrderMark && l.offset == 0 && l.err == nil {
+		l.pos = 0
+		l.read()
+	}
 	return l
 }
 
+const byteOrderMark rune = 0xFEFF
+
 // Next parses and returns the next token or returns an error.
 // If the lexer has stopped (after reading the last rune from the reader)
 // next will always return a eof token.
@@ -74,6 +144,7 @@ func newLexer(reader io.RuneReader) *lexer {
 // The returned token must not be retained by the caller.
 // The returned token is valid until the next invocation of next.
 func (l *lexer) next() (*token, error) {
+	l.mark = -1
 	// eat whitespace
 	for {
 		if l.err != nil {
@@ -85,47 +156,180 @@ func (l *lexer) next() (*token, error) {
 		l.read()
 	}
 	// scan next token
+	bol := l.bol
+	l.bol = false
+	tok := &l.tok
+	tok.line, tok.pos, tok.offset = l.line, l.pos, l.offset
+	tok.text, tok.quoted = nil, false
 	switch l.r {
 	case 0:
-		return &token{l.line, l.pos, eofToken, ""}, nil
+		tok.ttype, tok.end = eofToken, Position{tok.line, tok.pos}
+		return tok, nil
 	case '|':
-		line, pos := l.line, l.pos
 		l.read()
-		return &token{line, pos, separatorToken, ""}, nil
+		tok.ttype, tok.end = separatorToken, Position{tok.line, tok.pos + 1}
+		return tok, nil
 	case '\n':
-		line, pos := l.line, l.pos
 		l.read()
-		return &token{line, pos, newlineToken, ""}, nil
+		l.bol = true
+		tok.ttype, tok.end = newlineToken, Position{tok.line, tok.pos + 1}
+		return tok, nil
 	case '"':
-		line, pos := l.line, l.pos
 		text, err := l.readQuotedText()
 		if err != nil {
 			return nil, err
 		}
-		return &token{line, pos, textToken, text}, nil
-	default:
-		line, pos := l.line, l.pos
-		text, err := l.readText()
+		tok.ttype, tok.end = textToken, Position{l.line, l.pos}
+		tok.text, tok.quoted = text, true
+		return tok, nil
+	case '[':
+		if !l.lists {
+			break
+		}
+		text, err := l.readList()
 		if err != nil {
 			return nil, err
 		}
-		return &token{line, pos, textToken, text}, nil
+		tok.ttype, tok.end = textToken, Position{tok.line, tok.pos + utf8.RuneCount(text)}
+		tok.text = text
+		return tok, nil
+	case '#':
+		if !bol {
+			break
+		}
+		text, endPos, err := l.readComment()
+		if err != nil {
+			return nil, err
+		}
+		l.bol = true
+		tok.ttype, tok.end = commentToken, Position{tok.line, endPos}
+		tok.text = text
+		return tok, nil
+	}
+	text, err := l.readText()
+	if err != nil {
+		return nil, err
+	}
+	tok.ttype, tok.end = textToken, Position{tok.line, tok.pos + utf8.RuneCount(text)}
+	tok.text = text
+	return tok, nil
+}
+
+// readComment reads a comment line, including its newline.
+// Initially, l.r is the '#' that starts the comment. It returns the
+// text after the '#', without one leading space and without trailing
+// whitespace, and the pos after the last non-whitespace rune.
+func (l *lexer) readComment() ([]byte, int, error) {
+	endPos := l.pos + 1
+	l.read()
+	if l.r == ' ' {
+		l.read()
+	}
+	l.mark = l.index()
+	count, spaces := 0, 0
+	for {
+		if l.err != nil {
+			return nil, 0, l.err
+		}
+		if l.r == 0 || l.r == '\n' {
+			n := l.index() - l.mark
+			l.read()
+			text := bytes.TrimRight(l.buf[l.mark:l.mark+n], " \t\r")
+			return text, endPos, nil
+		}
+		if l.r == ' ' || l.r == '\t' || l.r == '\r' {
+			spaces++
+		} else {
+			count += spaces + 1
+			spaces = 0
+			if l.maxStringLen > 0 && count > l.maxStringLen {
+				return nil, 0, l.limitError("MaxStringLen", int64(l.maxStringLen))
+			}
+			endPos = l.pos + 1
+		}
+		l.read()
 	}
 }
 
 // readText will collect the next runes, up to (and not including) the next
 // separator or newline or EOF, whichever comes first.
-// It trims trailing whitespace.
-func (l *lexer) readText() (string, error) {
-	runes := make([]rune, 0, 40)
+// It trims trailing whitespace. Trailing whitespace, like the padding
+// of a column, does not count toward maxStringLen.
+func (l *lexer) readText() ([]byte, error) {
+	l.mark = l.index()
+	count, spaces := 0, 0
 	for {
 		if l.err != nil {
-			return "", l.err
+			return nil, l.err
 		}
 		if l.r == 0 || l.r == '|' || l.r == '\n' {
-			return strings.TrimSpace(string(runes)), nil
+			return bytes.TrimSpace(l.buf[l.mark:l.index()]), nil
+		}
+		if unicode.IsSpace(l.r) {
+			spaces++
+		} else {
+			count += spaces + 1
+			spaces = 0
+			if l.maxStringLen > 0 && count > l.maxStringLen {
+				return nil, l.limitError("MaxStringLen", int64(l.maxStringLen))
+			}
+		}
+		l.read()
+	}
+}
+
+// readList collects the runes of a list, see rfc.txt, section 3.12,
+// from the '[' up to the next ']' that is not within quotes, and then
+// up to the next separator or newline or EOF, like readText.
+// Quoted text within the list is kept as it is, escape sequences
+// are not decoded. A separator within quotes is part of the list.
+// A list must not span lines.
+func (l *lexer) readList() ([]byte, error) {
+	l.mark = l.index()
+	count, spaces := 0, 0
+	quoted, escaped, closed := false, false, false
+	for {
+		if l.err != nil {
+			return nil, l.err
+		}
+		if l.r == 0 || l.r == '\n' {
+			if !closed {
+				return nil, l.errorf("unterminated list")
+			}
+			return bytes.TrimSpace(l.buf[l.mark:l.index()]), nil
+		}
+		if closed {
+			if l.r == '|' {
+				return bytes.TrimSpace(l.buf[l.mark:l.index()]), nil
+			}
+			// whitespace after the list, like the padding of a
+			// column, does not count toward maxStringLen
+			if unicode.IsSpace(l.r) {
+				spaces++
+				l.read()
+				continue
+			}
+		}
+		count += spaces + 1
+		spaces = 0
+		if l.maxStringLen > 0 && count > l.maxStringLen {
+			return nil, l.limitError("MaxStringLen", int64(l.maxStringLen))
+		}
+		switch {
+		case closed:
+		case escaped:
+			escaped = false
+		case quoted:
+			if l.r == '\\' {
+				escaped = true
+			} else if l.r == '"' {
+				quoted = false
+			}
+		case l.r == '"':
+			quoted = true
+		case l.r == ']':
+			closed = true
 		}
-		runes = append(runes, l.r)
 		l.read()
 	}
 }
@@ -133,61 +337,107 @@ func (l *lexer) readText() (string, error) {
 // readQuotedText will collect the next runes, up to the
 // first unescaped double quote '"', which will close a quoted string.
 // Escaping applies, unicode escaping also.
-func (l *lexer) readQuotedText() (string, error) {
-	runes := make([]rune, 0, 40)
+// Whitespace within the quotes is significant and kept as it is.
+// The returned text is valid until the next call of readQuotedText.
+func (l *lexer) readQuotedText() ([]byte, error) {
+	l.scratch = l.scratch[:0]
+	l.runeCount = 0
+	l.read()
 	for {
-		l.read()
 		if l.err != nil {
-			return "", l.err
+			return nil, l.err
+		}
+		if l.maxStringLen > 0 && l.runeCount > l.maxStringLen {
+			return nil, l.limitError("MaxStringLen", int64(l.maxStringLen))
 		}
 		switch l.r {
 		case 0:
-			return "", l.errorf("unterminated string")
+			return nil, l.errorf("unterminated string")
 		case '\\':
-			r, err := l.readEscapeSequence()
+			err := l.readEscapeSequence()
 			if err != nil {
-				return "", err
+				return nil, err
 			}
-			runes = append(runes, r)
 		case '"':
 			l.read()
-			return strings.TrimSpace(string(runes)), nil
+			return l.scratch, nil
 		default:
-			runes = append(runes, l.r)
+			if l.strict && l.r < 0x20 {
+				return nil, l.errorf("unescaped control char 0x%x", l.r)
+			}
+			l.appendRune(l.r)
+			l.read()
 		}
 	}
 }
 
-func (l *lexer) readEscapeSequence() (rune, error) {
+// appendRune appends a rune to the scratch buffer.
+func (l *lexer) appendRune(r rune) {
+	if r < utf8.RuneSelf {
+		l.scratch = append(l.scratch, byte(r))
+	} else {
+		var enc [utf8.UTFMax]byte
+		n := utf8.EncodeRune(enc[:], r)
+		l.scratch = append(l.scratch, enc[:n]...)
+	}
+	l.runeCount++
+}
+
+// readEscapeSequence reads an escape sequence and appends the escaped
+// rune to the scratch buffer. Initially, l.r is the reverse solidus that
+// starts the escape sequence. Afterwards, l.r is the rune after the
+// escape sequence.
+func (l *lexer) readEscapeSequence() error {
 	l.read()
+	return l.readEscapedRune()
+}
+
+// readEscapedRune is like readEscapeSequence but l.r is the rune
+// after the reverse solidus.
+func (l *lexer) readEscapedRune() error {
+	var r rune
 	switch {
 	case l.err != nil:
-		return 0, l.err
+		return l.err
 	case l.r == 0:
-		return 0, l.errorf("unterminated escape sequence")
+		return l.errorf("unterminated escape sequence")
 	case l.r == 'b':
-		return '\b', nil
+		r = '\b'
 	case l.r == 't':
-		return '\t', nil
+		r = '\t'
 	case l.r == 'n':
-		return '\n', nil
+		r = '\n'
 	case l.r == 'f':
-		return '\f', nil
+		r = '\f'
 	case l.r == 'r':
-		return '\r', nil
+		r = '\r'
 	case l.r == 'u':
-		return l.readUniodeEscapeSequence()
+		unit, err := l.readUnicodeEscapeSequence()
+		if err != nil {
+			return err
+		}
+		return l.appendUTF16(unit)
 	case l.r == '"':
-		return '"', nil
+		r = '"'
 	case l.r == '\\':
-		return '\\', nil
+		r = '\\'
+	case l.r == '/':
+		r = '/'
+	default:
+		return l.errorf("illegal escape sequence")
 	}
-	return 0, l.errorf("illegal escape sequence")
+	l.read()
+	l.appendRune(r)
+	return nil
 }
 
-func (l *lexer) readUniodeEscapeSequence() (rune, error) {
-	runes := []rune{'\\', 'u', 0, 0, 0, 0}
-	for i := 2; i < 6; i++ {
+// readUnicodeEscapeSequence reads the four hex digits of a \uXXXX
+// escape sequence and returns their value, which is a UTF-16 code unit.
+// Initially, l.r is the 'u'. Afterwards, l.r is the rune after the
+// last hex digit.
+func (l *lexer) readUnicodeEscapeSequence() (rune, error) {
+	var unit rune
+	for i := 0; i < 4; i++ {
 		l.read()
 		if l.err != nil {
 			return 0, l.err
@@ -195,16 +445,158 @@ func (l *lexer) readUniodeEscapeSequence() (rune, error) {
 		if l.r == 0 {
 			return 0, l.errorf("unterminated escape sequence")
 		}
-		runes[i] = l.r
+		switch {
+		case '0' <= l.r && l.r <= '9':
+			unit = unit<<4 | (l.r - '0')
+		case 'a' <= l.r && l.r <= 'f':
+			unit = unit<<4 | (l.r - 'a' + 10)
+		case 'A' <= l.r && l.r <= 'F':
+			unit = unit<<4 | (l.r - 'A' + 10)
+		default:
+			return 0, l.errorf("illegal escape sequence")
+		}
 	}
-	value, _, _, err := strconv.UnquoteChar(string(runes), 0)
-	return value, err
+	l.read()
+	return unit, nil
+}
+
+// appendUTF16 appends a UTF-16 code unit to the scratch buffer. If unit
+// is a high surrogate and the next escape sequence is a low surrogate,
+// both are combined into one rune. Lone surrogates are errors in strict
+// mode, otherwise they are replaced by U+FFFD.
+func (l *lexer) appendUTF16(unit rune) error {
+	if !utf16.IsSurrogate(unit) {
+		l.appendRune(unit)
+		return nil
+	}
+	if unit >= 0xDC00 || l.r != '\\' {
+		return l.appendLoneSurrogate()
+	}
+	// a high surrogate, followed by another escape sequence
+	l.read()
+	if l.err != nil {
+		return l.err
+	}
+	if l.r != 'u' {
+		err := l.appendLoneSurrogate()
+		if err != nil {
+			return err
+		}
+		return l.readEscapedRune()
+	}
+	low, err := l.readUnicodeEscapeSequence()
+	if err != nil {
+		return err
+	}
+	if 0xDC00 <= low && low <= 0xDFFF {
+		l.appendRune(utf16.DecodeRune(unit, low))
+		return nil
+	}
+	err = l.appendLoneSurrogate()
+	if err != nil {
+		return err
+	}
+	return l.appendUTF16(low)
 }
 
-// read advances the lexer by reading the next rune from the reader.
-// If the reader has no more runes (eof), special rune 0 is set as
+func (l *lexer) appendLoneSurrogate() error {
+	if l.strict {
+		return l.errorf("lone surrogate in escape sequence")
+	}
+	l.appendRune(utf8.RuneError)
+	return nil
+}
+
+// skipRow skips the rest of a row, after a separator, up to (and not
+// including) the newline that ends the row. Other than skipLine, it
+// skips quoted text that spans lines, and separators within lists.
+// Lists tells, for each column, whether it is a list column, see
+// listColumns. It does not decode the text.
+func (l *lexer) skipRow(lists []bool) {
+	col := 0
+	start, quoted, list := true, false, false
+	for l.err == nil && l.r != 0 {
+		switch {
+		case quoted:
+			if l.r == '\\' {
+				l.read()
+			} else if l.r == '"' {
+				quoted = false
+			}
+		case l.r == '\n':
+			return
+		case list:
+			if l.r == '"' {
+				quoted = true
+			} else if l.r == ']' {
+				list = false
+			}
+		case l.r == '|':
+			start = true
+			col++
+		case l.r == '"' && start:
+			quoted, start = true, false
+		case l.r == '[' && start && col < len(lists) && lists[col]:
+			list, start = true, false
+		case l.r > ' ':
+			start = false
+		}
+		l.read()
+	}
+}
+
+// skipHeader skips the rest of a header line, after the first
+// separator, like skipRow. It returns the list columns of the header,
+// see listColumns.
+func (l *lexer) skipHeader() []bool {
+	l.mark = l.index()
+	l.skipRow(nil)
+	if l.err != nil {
+		return nil
+	}
+	return listColumns(l.buf[l.mark:l.index()])
+}
+
+// listColumns returns, for each column definition of a header line,
+// whether it defines a list column, see rfc.txt, section 3.12. The
+// line is the text after the first separator, up to the newline.
+// It checks the syntax only as far as needed for lexing.
+func listColumns(line []byte) []bool {
+	var lists []bool
+	for _, def := range bytes.Split(line, []byte("|")) {
+		i := bytes.IndexByte(def, ':')
+		lists = append(lists, i >= 0 && bytes.HasPrefix(bytes.TrimSpace(def[i+1:]), []byte("[")))
+	}
+	return lists
+}
+
+// skipLine skips all runes up to (and not including) the next newline.
+// Errors from previous reads are discarded, unless they are I/O errors.
+func (l *lexer) skipLine() {
+	for {
+		if l.err != nil {
+			if e, ok := l.err.(*ParseError); !ok || isLimitError(e) {
+				return
+			}
+			l.err = nil
+		}
+		if l.r == 0 || l.r == '\n' {
+			return
+		}
+		l.read()
+	}
+}
+
+// index returns the index of the current rune in the buffer.
+func (l *lexer) index() int {
+	return int(l.offset - l.base)
+}
+
+// read advances the lexer by decoding the next rune from the buffer.
+// If the input has no more runes (eof), special rune 0 is set as
 // the current rune and no error is set.
 // read will also update line and pos.
+// Invalid UTF-8 encodings are errors.
 // Rune codepoint U+0000 ocurring in the input is considered an error:
 // Valid input must not contain '\0' characters.
 func (l *lexer) read() {
@@ -217,16 +609,33 @@ func (l *lexer) read() {
 	} else {
 		l.pos++
 	}
-	r, _, err := l.reader.ReadRune()
-	if err != nil {
-		if err == io.EOF {
-			l.r = 0
-			l.err = nil
+	if len(l.buf)-l.i < utf8.UTFMax {
+		l.fill()
+	}
+	l.offset = l.base + int64(l.i)
+	if l.i >= len(l.buf) {
+		if l.readErr != nil && l.readErr != io.EOF {
+			l.err = l.readErr
 		} else {
-			l.err = err
+			l.r = 0
 		}
 		return
 	}
+	r, size := rune(l.buf[l.i]), 1
+	if r >= utf8.RuneSelf {
+		r, size = utf8.DecodeRune(l.buf[l.i:])
+	}
+	l.i += size
+	if l.maxBytes > 0 && l.base+int64(l.i) > l.maxBytes {
+		l.err = l.limitError("MaxBytes", l.maxBytes)
+		return
+	}
+	if r == utf8.RuneError && size == 1 {
+		l.r = r
+		l.err = l.errorf("invalid UTF-8 encoding at byte offset %d", l.offset)
+		return
+	}
+	var err error
 	if r < 0x20 && (r != 0x09 && r != 0x0A && r != 0x0D) {
 		err = l.errorf("invalid char 0x%x", r)
 	}
@@ -234,7 +643,79 @@ func (l *lexer) read() {
 	l.err = err
 }
 
+// fill reads input from the reader into the buffer, until the buffer
+// holds at least one complete rune after the current rune, or the
+// reader fails. It discards the bytes before the current rune, or before
+// mark, and grows the buffer if the remaining bytes do not leave
+// enough room.
+func (l *lexer) fill() {
+	if l.reader == nil || l.readErr != nil {
+		return
+	}
+	keep := l.i
+	if l.mark >= 0 && l.mark < keep {
+		keep = l.mark
+	}
+	if keep > 0 {
+		n := copy(l.buf, l.buf[keep:])
+		l.buf = l.buf[:n]
+		l.i -= keep
+		l.base += int64(keep)
+		if l.mark >= 0 {
+			l.mark -= keep
+		}
+	}
+	for empty := 0; len(l.buf)-l.i < utf8.UTFMax; {
+		if cap(l.buf)-len(l.buf) < utf8.UTFMax {
+			buf := make([]byte, len(l.buf), 2*cap(l.buf)+utf8.UTFMax)
+			copy(buf, l.buf)
+			l.buf = buf
+		}
+		n, err := l.reader.Read(l.buf[len(l.buf):cap(l.buf)])
+		l.buf = l.buf[:len(l.buf)+n]
+		if err != nil {
+			l.readErr = err
+			return
+		}
+		if n == 0 {
+			empty++
+			if empty >= 100 {
+				l.readErr = io.ErrNoProgress
+				return
+			}
+		}
+	}
+}
+
 func (l *lexer) errorf(format string, args ...interface{}) error {
-	msg := fmt.Sprintf(format, args...)
-	return fmt.Errorf("line %d, pos %d: %s", l.line, l.pos, msg)
+	cause := fmt.Errorf(format, args...)
+	return &ParseError{Line: l.line, Pos: l.pos, RowIndex: -1, Cause: cause}
+}
+
+func (l *lexer) limitError(limit string, max int64) error {
+	cause := &LimitExceededError{limit, max}
+	return &ParseError{Line: l.line, Pos: l.pos, RowIndex: -1, Cause: cause}
+}
+
+// runeReader adapts an io.RuneReader to an io.Reader.
+type runeReader struct {
+	reader io.RuneReader
+}
+
+func (r runeReader) Read(p []byte) (int, error) {
+	n := 0
+	for n+utf8.UTFMax <= len(p) {
+		c, size, err := r.reader.ReadRune()
+		if err != nil {
+			return n, err
+		}
+		if c == utf8.RuneError && size == 1 {
+			// keep invalid encodings invalid
+			p[n] = 0xff
+			n++
+			continue
+		}
+		n += utf8.EncodeRune(p[n:], c)
+	}
+	return n, nil
 }
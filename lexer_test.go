@@ -125,6 +125,55 @@ func assertNext(t *testing.T, lex *lexer, exp string) {
 	assert.EqStr(t, exp, act)
 }
 
+func TestLexerList(t *testing.T) {
+	input := "|[\"a|b\", \"c]\"] |[]x\n"
+	input += "|[\"\\\"|\"]|\n"
+	input += "|[1,\n"
+	lex := newLexer(bytes.NewBufferString(input))
+	lex.lists = true
+	assertNext(t, lex, "1:1 separator()")
+	assertNext(t, lex, "1:2 text([\"a|b\", \"c]\"])")
+	assertNext(t, lex, "1:16 separator()")
+	assertNext(t, lex, "1:17 text([]x)")
+	assertNext(t, lex, "1:20 newline()")
+	assertNext(t, lex, "2:1 separator()")
+	assertNext(t, lex, "2:2 text([\"\\\"|\"])")
+	assertNext(t, lex, "2:9 separator()")
+	assertNext(t, lex, "2:10 newline()")
+	assertNext(t, lex, "3:1 separator()")
+	assertNext(t, lex, "line 3, pos 5: unterminated list")
+	// without lists, '[' starts a text like any other rune
+	lex = newLexer(bytes.NewBufferString("[t\n|[draft|[\"a|b\"]\n"))
+	assertNext(t, lex, "1:1 text([t)")
+	assertNext(t, lex, "1:3 newline()")
+	assertNext(t, lex, "2:1 separator()")
+	assertNext(t, lex, "2:2 text([draft)")
+	assertNext(t, lex, "2:8 separator()")
+	assertNext(t, lex, "2:9 text([\"a)")
+	assertNext(t, lex, "2:12 separator()")
+	assertNext(t, lex, "2:13 text(b\"])")
+}
+
+func TestLexerComment(t *testing.T) {
+	lex := newLexer(bytes.NewBufferString("# c1\n  #c2  \na#|#\n\t# c3 # \r\n#"))
+	assertNext(t, lex, "1:1 comment(c1)")
+	assertNext(t, lex, "2:3 comment(c2)")
+	assertNext(t, lex, "3:1 text(a#)")
+	assertNext(t, lex, "3:3 separator()")
+	assertNext(t, lex, "3:4 text(#)")
+	assertNext(t, lex, "3:5 newline()")
+	assertNext(t, lex, "4:2 comment(c3 #)")
+	assertNext(t, lex, "5:1 comment()")
+	assertNext(t, lex, "5:2 eof()")
+}
+
+func TestLexerByteOrderMark(t *testing.T) {
+	lex := newLexer(bytes.NewBufferString("\ufeffpersons\n|id:i"))
+	assertNext(t, lex, "1:1 text(persons)")
+	assertNext(t, lex, "1:8 newline()")
+	assertNext(t, lex, "2:1 separator()")
+}
+
 func TestLexerReadText(t *testing.T) {
 	testCases := []struct {
 		name  string
@@ -146,6 +195,11 @@ func TestLexerReadText(t *testing.T) {
 		{"text_71", "\"\\u2602 ☂\"  |", "☂ ☂"},
 		{"text_72", "\"\"ab\"\"  \n", ""},
 		{"text_73", "\"hello\" \u0006", "hello"},
+		{"text_74", "\"  padded  \"  |", "  padded  "},
+		{"text_75", "\"\t\\t \"\n", "\t\t "},
+		{"text_76", "\ufeffabc", "abc"},
+		{"text_77", "a\ufeffb", "a\ufeffb"},
+		{"text_78", "\"\ufffd\"", "\ufffd"},
 		{"err_11", "\"a", "line 1, pos 3: unterminated string"},
 		{"err_12", "\"☂", "line 1, pos 3: unterminated string"},
 		{"err_13", "\"\r", "line 1, pos 3: unterminated string"},
@@ -154,17 +208,20 @@ func TestLexerReadText(t *testing.T) {
 		{"err_22", "\"\\e", "line 1, pos 3: illegal escape sequence"},
 		{"err_23", string([]byte{2}), "line 1, pos 1: invalid char 0x2"},
 		{"err_24", string([]byte{'a', 1}), "line 1, pos 2: invalid char 0x1"},
+		{"err_25", "a\xffb", "line 1, pos 2: invalid UTF-8 encoding at byte offset 1"},
+		{"err_26", "\"\u2602\xe2\x98\"", "line 1, pos 3: invalid UTF-8 encoding at byte offset 4"},
+		{"err_27", "\ufeff\xc0", "line 1, pos 1: invalid UTF-8 encoding at byte offset 3"},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			lex := newLexer(bytes.NewBufferString(testCase.input))
-			text, err := "", error(nil)
+			text, err := []byte(nil), error(nil)
 			if testCase.input[0] == '"' {
 				text, err = lex.readQuotedText()
 			} else {
 				text, err = lex.readText()
 			}
-			act := text
+			act := string(text)
 			if err != nil {
 				act += err.Error()
 			}
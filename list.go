@@ -0,0 +1,94 @@
+package tdat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// parseList parses text, which the lexer scanned as a list, into v.
+// The elements are separated by commas and parsed like cells of
+// the element type, see rfc.txt, section 3.12.
+func (p *parser) parseList(v *Value, column *Column, text []byte) error {
+	if len(text) < 2 || text[0] != '[' || text[len(text)-1] != ']' {
+		return fmt.Errorf("cannot parse as list: invalid syntax")
+	}
+	elem := column.elemColumn()
+	inner := bytes.TrimSpace(text[1 : len(text)-1])
+	if len(inner) == 0 {
+		v.AsCustom = []*Value{}
+		return nil
+	}
+	texts := splitList(inner)
+	values := make([]Value, len(texts))
+	list := make([]*Value, len(texts))
+	for k, text := range texts {
+		text = bytes.TrimSpace(text)
+		quoted := len(text) > 0 && text[0] == '"'
+		if p.opts.Strict && quoted != (elem.Type == StringValue) {
+			if quoted {
+				return fmt.Errorf("element %d: value must not be quoted", k+1)
+			}
+			return fmt.Errorf("element %d: string value must be quoted", k+1)
+		}
+		if quoted {
+			s, err := p.unquote(text)
+			if err != nil {
+				return fmt.Errorf("element %d: %s", k+1, err)
+			}
+			text = s
+		} else if len(text) == 0 {
+			return fmt.Errorf("element %d is empty", k+1)
+		}
+		err := p.parseValue(&values[k], elem, text)
+		if err != nil {
+			return fmt.Errorf("element %d: %s", k+1, err)
+		}
+		list[k] = &values[k]
+	}
+	v.AsCustom = list
+	return nil
+}
+
+// splitList splits the text between the brackets of a list at the
+// commas that are not within quotes.
+func splitList(text []byte) [][]byte {
+	var texts [][]byte
+	start, quoted := 0, false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quoted && text[i] == '\\':
+			i++
+		case text[i] == '"':
+			quoted = !quoted
+		case text[i] == ',' && !quoted:
+			texts = append(texts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(texts, text[start:])
+}
+
+// unquote decodes a quoted string element of a list, as defined
+// in rfc.txt, section 3.4.
+func (p *parser) unquote(text []byte) ([]byte, error) {
+	l := newBytesLexer(text)
+	l.strict = p.opts.Strict
+	s, err := l.readQuotedText()
+	if err != nil {
+		return nil, err.(*ParseError).Cause
+	}
+	if l.r != 0 {
+		return nil, fmt.Errorf("unexpected text after string")
+	}
+	return s, nil
+}
+
+// formatList formats a list value as a cell text.
+func formatList(val *Value, exactTimes bool) string {
+	texts := make([]string, len(val.List()))
+	for k, elem := range val.List() {
+		texts[k] = formatValue(elem, exactTimes)
+	}
+	return "[" + strings.Join(texts, ",") + "]"
+}
@@ -0,0 +1,182 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	input := "t\n"
+	input += "|id:i|tags:[s]|scores:[i]|states:[e(a,b)]\n"
+	input += "|1|[\"red\", \"a|b, \\\"c\\\"\"]|[1, 2,3]|[a,b,a]\n"
+	input += "|2|[]|[ ]|[]\n"
+	input += "|3|||\n"
+	model, err := ParseOptions{Strict: true, Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	table := model.Tables[0]
+	assert.EqStr(t, "[s]", fmt.Sprintf("[%c]", table.Columns[1].ElemType))
+	tags := table.ListAt(0, 1)
+	assert.EqInt(t, 2, len(tags))
+	assert.EqStr(t, "red", tags[0].AsString)
+	assert.EqStr(t, "a|b, \"c\"", tags[1].AsString)
+	assert.EqStr(t, "[1 2 3]", fmt.Sprintf("%v", []int64{table.ListAt(0, 2)[0].AsInt, table.ListAt(0, 2)[1].AsInt, table.ListAt(0, 2)[2].AsInt}))
+	assert.EqInt(t, 3, len(table.ListAt(0, 3)))
+	assert.True(t, !table.IsNull(1, 1))
+	assert.EqInt(t, 0, len(table.ListAt(1, 2)))
+	assert.True(t, table.IsNull(2, 1))
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "t\n"
+	exp += "|id:i|tags:[s]|scores:[i]|states:[e(a,b)]\n"
+	exp += "|1|[\"red\",\"a|b, \\\"c\\\"\"]|[1,2,3]|[a,b,a]\n"
+	exp += "|2|[]|[]|[]\n"
+	exp += "|3|||\n"
+	exp += "\n"
+	assert.EqStr(t, exp, txt)
+	// the rendered text parses to the same model
+	model, err = ParseOptions{Strict: true}.ParseFromString(txt)
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, exp, txt)
+}
+
+func TestParseListErrors(t *testing.T) {
+	testCases := []struct {
+		column  string
+		cell    string
+		strict  string
+		lenient string
+	}{
+		{"[i]", "[1,x]", "element 2: cannot parse as int: invalid syntax", "element 2: cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax"},
+		{"[i]", "[1,,2]", "element 2 is empty", "element 2 is empty"},
+		{"[i]", "[\"1\"]", "element 1: value must not be quoted", "[1]"},
+		{"[s]", "[\"a\",b]", "element 2: string value must be quoted", "[a b]"},
+		{"[s]", "[\"a\"x]", "element 1: unexpected text after string", "element 1: unexpected text after string"},
+		{"[s]", "[\"\\x\"]", "element 1: illegal escape sequence", "element 1: illegal escape sequence"},
+		{"[s]", "[\"a\"]x", "cannot parse as list: invalid syntax", "cannot parse as list: invalid syntax"},
+		{"[s]", "[\"a\"", "unterminated list", "unterminated list"},
+		{"[e(a,b)]", "[a,c]", "element 2: cannot parse as enum: \"c\" is not one of a,b", "element 2: cannot parse as enum: \"c\" is not one of a,b"},
+		{"[D]", "[2017-02-29]", "element 1: cannot parse as date: parsing time \"2017-02-29\": day out of range", "element 1: cannot parse as date: parsing time \"2017-02-29\": day out of range"},
+		{"[z]", "[]", "invalid column type", "invalid column type"},
+		{"[[i]]", "[]", "invalid column definition", "invalid column definition"},
+		{"[e]", "[]", "invalid column definition: enum has no symbols", "invalid column definition: enum has no symbols"},
+		{"[e(a,[b])]", "[]", "invalid column definition: symbol \"[b]\" contains invalid character '['", "invalid column definition: symbol \"[b]\" contains invalid character '['"},
+	}
+	for _, testCase := range testCases {
+		input := fmt.Sprintf("t\n|c:%s\n|%s\n", testCase.column, testCase.cell)
+		for _, strict := range []bool{true, false} {
+			exp := testCase.lenient
+			if strict {
+				exp = testCase.strict
+			}
+			model, err := ParseOptions{Strict: strict}.ParseFromString(input)
+			act := ""
+			if err != nil {
+				act = err.(*ParseError).Cause.Error()
+			} else {
+				act = fmt.Sprintf("%v", stringifyList(model.Tables[0].Rows[0].Values[0]))
+			}
+			assert.EqStrf(t, exp, act, "column %s cell %s strict %t", testCase.column, testCase.cell, strict)
+		}
+	}
+}
+
+func TestParseListSkipped(t *testing.T) {
+	input := "a\n|x:[s]\n|[\"a|\"]\nb\n|y:s\n|\"c\"\n"
+	model, err := ParseOptions{Tables: []string{"b"}}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 1, len(model.Tables))
+	assert.EqStr(t, "c", model.Tables[0].Rows[0].Values[0].AsString)
+	model, err = ParseOptions{Workers: 2}.ParseBytes([]byte(input))
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 2, len(model.Tables))
+	assert.EqStr(t, "a|", model.Tables[0].Rows[0].Values[0].List()[0].AsString)
+}
+
+func TestParseBracketText(t *testing.T) {
+	// '[' starts a list in list columns only
+	input := "[t\n|a:s|b:i|c:[s]\n|[draft|1|[\"|\"]\n|[a|2|[]\n"
+	input += "u\n|a:s|b:s\n|[x|\"y\nz\"\n"
+	check := func(model *Model, err error, tables int) {
+		assert.Truef(t, err == nil, "err was %s", err)
+		assert.EqInt(t, tables, len(model.Tables))
+		table := model.Tables[len(model.Tables)-1]
+		assert.EqStr(t, "y\nz", table.Rows[0].Values[1].AsString)
+		if tables == 1 {
+			return
+		}
+		table = model.Tables[0]
+		assert.EqStr(t, "[t", table.Name)
+		assert.EqStr(t, "[draft", table.Rows[0].Values[0].AsString)
+		assert.EqInt(t, 1, int(table.Rows[0].Values[1].AsInt))
+		assert.EqStr(t, "|", table.Rows[0].Values[2].List()[0].AsString)
+		assert.EqStr(t, "[a", table.Rows[1].Values[0].AsString)
+	}
+	model, err := ParseFromString(input)
+	check(model, err, 2)
+	model, err = ParseOptions{Tables: []string{"u"}}.ParseFromString(input)
+	check(model, err, 1)
+	model, err = ParseOptions{Workers: 2}.ParseBytes([]byte(input))
+	check(model, err, 2)
+	doc, err := ParseDocumentFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input, doc.String())
+	err = doc.SetCell("[t", 0, "a", &Value{Type: StringValue, AsString: "x"})
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, strings.Replace(input, "[draft", "\"x\"", 1), doc.String())
+}
+
+func TestBuildList(t *testing.T) {
+	builder := NewBuilder()
+	table := builder.AddTable("t")
+	table.AddListColumn("tags", StringValue)
+	table.AddEnumListColumn("states", "a", "b")
+	row := table.AddRow()
+	row.AddListValue([]*Value{{Type: StringValue, AsString: "x"}, {Type: StringValue, AsString: "y"}})
+	row.AddListValue([]*Value{{Type: EnumValue, AsString: "b"}})
+	row = table.AddRow()
+	row.AddListValue(nil)
+	row.AddListValue([]*Value{})
+	model, err := builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "t\n|tags:[s]|states:[e(a,b)]\n|[\"x\",\"y\"]|[b]\n||[]\n\n", txt)
+	// invalid elements
+	testCases := []struct {
+		elem *Value
+		exp  string
+	}{
+		{nil, "table \"t\": row 1, value 2: element 1 is null"},
+		{&Value{Type: EnumValue, Null: true}, "table \"t\": row 1, value 2: element 1 is null"},
+		{&Value{Type: StringValue, AsString: "a"}, "table \"t\": row 1, value 2: element 1: expected value type 'e' but was 's'"},
+		{&Value{Type: EnumValue, AsString: "c"}, "table \"t\": row 1, value 2: element 1: \"c\" is not a symbol of column \"states\""},
+	}
+	for _, testCase := range testCases {
+		builder := NewBuilder()
+		table := builder.AddTable("t")
+		table.AddListColumn("tags", StringValue)
+		table.AddEnumListColumn("states", "a", "b")
+		row := table.AddRow()
+		row.AddListValue(nil)
+		row.AddListValue([]*Value{testCase.elem})
+		_, err := builder.Build()
+		assert.EqStr(t, testCase.exp, err.Error())
+	}
+	// invalid columns
+	err = validateColumns([]*Column{{Name: "c", Type: ListValue, ElemType: ListValue}})
+	assert.EqStr(t, "column \"c\" has invalid element type '['", err.Error())
+	err = validateColumns([]*Column{{Name: "c", Type: IntValue, ElemType: IntValue}})
+	assert.EqStr(t, "column \"c\": element type is only allowed for type '['", err.Error())
+}
+
+func stringifyList(val *Value) []string {
+	var texts []string
+	for _, elem := range val.List() {
+		texts = append(texts, stringifyValue(elem))
+	}
+	return texts
+}
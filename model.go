@@ -1,6 +1,7 @@
 package tdat
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -8,9 +9,13 @@ import (
 type Model struct {
 	// The tables of the model
 	Tables []*Table
+	// Comments holds the comment lines after the last table
+	Comments []string
 }
 
 // A Table contains zero or more columns and zero or more rows.
+// The rows of a table in columnar form are not held in Rows,
+// see Table.IsColumnar.
 type Table struct {
 	// Name is the name of the table
 	Name string
@@ -18,18 +23,111 @@ type Table struct {
 	Columns []*Column
 	// A slice of data rows
 	Rows []*Row
+	// Comments holds the comment lines before the table name
+	Comments []string
+	// the positions of the table in the input, see Table.Span
+	spans *tableSpans
+	// the values of a table in columnar form, see columnar.go
+	data        []*columnData
+	rowCount    int
+	rowComments map[int][]string
+}
+
+// Span returns the position of the table in the input, from the start
+// of its name up to the end of its last row. The span is only known
+// if the table was parsed with ParseOptions.Positions, otherwise
+// Span returns the zero Span.
+func (t *Table) Span() Span {
+	if t.spans == nil {
+		return Span{}
+	}
+	return t.spans.table
+}
+
+// ColumnSpan returns the position of the definition of column j in
+// the input. See Table.Span.
+func (t *Table) ColumnSpan(j int) Span {
+	if t.spans == nil || j < 0 || j >= len(t.spans.columns) {
+		return Span{}
+	}
+	return t.spans.columns[j]
+}
+
+// RowSpan returns the position of row i in the input, from its first
+// separator up to the end of its last cell. See Table.Span.
+func (t *Table) RowSpan(i int) Span {
+	if t.spans == nil || i < 0 || i >= len(t.spans.rows) {
+		return Span{}
+	}
+	return t.spans.rows[i]
+}
+
+// ValueSpan returns the position of the value in row i and column j in
+// the input. Padding and quotes are not part of the value, but quotes
+// are part of its span. The span of a null value is empty, it starts
+// and ends right after the separator of its cell. See Table.Span.
+func (t *Table) ValueSpan(i, j int) Span {
+	s := t.spans
+	if s == nil || i < 0 || i >= len(s.rows) || j < 0 || j >= len(s.columns) {
+		return Span{}
+	}
+	return s.values[i*len(s.columns)+j]
+}
+
+// tableSpans holds the positions of a table and of its columns, rows
+// and values in the input. It is only allocated if the table was parsed
+// with ParseOptions.Positions, so that Column, Row and Value do not pay
+// for them. Spans are kept by index, they refer to the columns and rows
+// as parsed. Values holds one span per column for each row.
+type tableSpans struct {
+	table   Span
+	columns []Span
+	rows    []Span
+	values  []Span
 }
 
 // A Column has a name and a type.
 type Column struct {
 	Name string
 	Type ValueType
+	// ElemType is the type of the elements of a ListValue column.
+	// It is not used for other columns.
+	ElemType ValueType
+	// Symbols holds the allowed values of an EnumValue column, or
+	// of the elements of a ListValue column of enums, in the order
+	// of the column definition.
+	Symbols []string
+	// Comments holds the comment lines before the column header.
+	// A parser attaches them to the first column of a table.
+	Comments []string
+	elem     *Column
+}
+
+// elemColumn returns a column that describes the elements
+// of a ListValue column.
+func (c *Column) elemColumn() *Column {
+	if c.elem == nil {
+		c.elem = &Column{Name: c.Name, Type: c.ElemType, Symbols: c.Symbols}
+	}
+	return c.elem
+}
+
+// symbol returns the symbol of the column that equals text.
+func (c *Column) symbol(text []byte) (string, bool) {
+	for _, s := range c.Symbols {
+		if s == string(text) {
+			return s, true
+		}
+	}
+	return "", false
 }
 
 // Row contains zero or more values.
 type Row struct {
 	// Each value is either a int, a float, a bool, etc.
 	Values []*Value
+	// Comments holds the comment lines before the row
+	Comments []string
 }
 
 // ValueType represents the type of a column or value.
@@ -51,18 +149,53 @@ const (
 
 	// TimeValue represents a time.Time value. Its code is 't'.
 	TimeValue = 't'
+
+	// DecimalValue represents a Decimal value. Its code is 'd'.
+	DecimalValue = 'd'
+
+	// DateValue represents a date, held in a time.Time value at
+	// midnight. Its code is 'D'.
+	DateValue = 'D'
+
+	// DurationValue represents a time.Duration value. Its code is 'P'.
+	DurationValue = 'P'
+
+	// BytesValue represents a []byte value. Its code is 'B'.
+	BytesValue = 'B'
+
+	// UUIDValue represents a UUID value. Its code is 'U'.
+	UUIDValue = 'U'
+
+	// EnumValue represents one of the symbols of a column, held in
+	// a string value. Its code is 'e'.
+	EnumValue = 'e'
+
+	// ListValue represents a list of values of the element type
+	// of a column. Its code is '['.
+	ListValue = '['
 )
 
-// IsValid returns true if t is a valid ValueType.
+// IsValid returns true if t is a valid ValueType, that is, a built-in
+// type or a type registered with RegisterType.
 func (t ValueType) IsValid() bool {
+	return isBuiltinType(t) || lookupType(t) != nil
+}
+
+func isBuiltinType(t ValueType) bool {
 	switch t {
-	case 'i', 'f', 'b', 's', 't':
+	case 'i', 'f', 'b', 's', 't', 'd', 'D', 'P', 'B', 'U', 'e', '[':
 		return true
 	}
 	return false
 }
 
 // Value represents a value in a table row.
+//
+// Every cell of a table in row form is a Value, so Value has one field
+// per Go type that is used often: AsInt, AsFloat, AsBool, AsString and
+// AsTime. The other built-in types share AsCustom, durations are held
+// in AsInt. Read them with the methods Decimal, Duration, Bytes, UUID
+// and List. On 64-bit platforms, a Value takes 88 bytes.
 type Value struct {
 
 	// The type of the Value (IntValue, FloatValue, etc.).
@@ -71,7 +204,8 @@ type Value struct {
 	// Null is true if this Value is null (undefined, nil, nothing).
 	Null bool
 
-	// Holds the value for IntValue.
+	// Holds the value for IntValue, and for DurationValue
+	// in nanoseconds, see Value.Duration.
 	AsInt int64
 
 	// Holds the value for FloatValue.
@@ -80,9 +214,113 @@ type Value struct {
 	// Holds the value for BoolValue.
 	AsBool bool
 
-	// Holds the value for StringValue.
+	// Holds the value for StringValue and EnumValue.
 	AsString string
 
-	// Holds the value for TimeValue.
+	// Holds the value for TimeValue and DateValue.
 	AsTime time.Time
+
+	// Holds the value for the types that are used less often, so
+	// that they do not increase the size of every Value:
+	//   - a Decimal for DecimalValue
+	//   - a []byte for BytesValue
+	//   - a UUID for UUIDValue
+	//   - a []*Value for ListValue, the elements of the list,
+	//     which are never null
+	//   - the value for a type registered with RegisterType.
+	// For BytesValue and ListValue, nil means empty.
+	// See Value.Decimal, Value.Bytes, Value.UUID and Value.List.
+	AsCustom interface{}
+}
+
+// Decimal returns the value of a DecimalValue, or the zero
+// Decimal if AsCustom does not hold a Decimal.
+func (v *Value) Decimal() Decimal {
+	x, _ := v.AsCustom.(Decimal)
+	return x
+}
+
+// Duration returns the value of a DurationValue.
+func (v *Value) Duration() time.Duration {
+	return time.Duration(v.AsInt)
+}
+
+// Bytes returns the value of a BytesValue, or nil if
+// AsCustom does not hold a []byte.
+func (v *Value) Bytes() []byte {
+	x, _ := v.AsCustom.([]byte)
+	return x
+}
+
+// UUID returns the value of a UUIDValue, or the zero UUID
+// if AsCustom does not hold a UUID.
+func (v *Value) UUID() UUID {
+	x, _ := v.AsCustom.(UUID)
+	return x
+}
+
+// List returns the elements of a ListValue, or nil if
+// AsCustom does not hold a []*Value.
+func (v *Value) List() []*Value {
+	x, _ := v.AsCustom.([]*Value)
+	return x
+}
+
+// checkCustom returns an error if AsCustom of a non-null value
+// does not hold the Go type that belongs to the value type.
+func checkCustom(v *Value) error {
+	ok := true
+	switch v.Type {
+	case DecimalValue:
+		_, ok = v.AsCustom.(Decimal)
+	case BytesValue:
+		_, ok = v.AsCustom.([]byte)
+		ok = ok || v.AsCustom == nil
+	case UUIDValue:
+		_, ok = v.AsCustom.(UUID)
+	case ListValue:
+		_, ok = v.AsCustom.([]*Value)
+		ok = ok || v.AsCustom == nil
+	}
+	if !ok {
+		return fmt.Errorf("value of type '%c' holds %T", v.Type, v.AsCustom)
+	}
+	return nil
+}
+
+// A Position is a location in a TDAT text. Line and Pos are 1-based,
+// Pos counts runes, not bytes. The zero Position is unknown.
+type Position struct {
+	Line int
+	Pos  int
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "line:pos", or "-" if
+// the position is unknown.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Pos)
+}
+
+// A Span is a range of text. Start is the position of the first rune,
+// End is the position after the last rune.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// String returns the span as "line:pos-line:pos", or "-" if
+// the span is unknown.
+func (s Span) String() string {
+	if !s.Start.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
 }
@@ -0,0 +1,234 @@
+package tdat
+
+import (
+	"context"
+	"sync"
+)
+
+// A segment is a part of the input that holds one table. It starts
+// at byte offset start, in line line.
+type segment struct {
+	start int
+	line  int
+}
+
+// splitTables splits input into segments, one segment per table.
+// A segment starts at the table name line, or at the first of the
+// blank and comment lines that precede the table name line, because
+// these comments belong to the table. The first segment always starts
+// at the beginning of the input.
+//
+// splitTables scans tokens the way the lexer does, so that it does not
+// mistake the lines of a quoted string for table names. For valid input,
+// every segment starts where the parser would start a new table.
+func splitTables(input []byte) []segment {
+	segments := []segment{{0, 1}}
+	tableCount := 0
+	line := 1
+	i := 0
+	if len(input) >= 3 && string(input[:3]) == "\xef\xbb\xbf" {
+		i = 3
+	}
+	// runStart and runLine locate the blank and comment lines
+	// after the last row or table name line
+	runStart, runLine := -1, 0
+	// header is true after a table name line, until the header
+	// line, lists are the list columns of the current table
+	header := false
+	var lists []bool
+	for i < len(input) {
+		lineStart := i
+		i = skipSpace(input, i)
+		c := byte('\n')
+		if i < len(input) {
+			c = input[i]
+		}
+		switch c {
+		case '\n', '#':
+			// blank line or comment line
+			if runStart < 0 {
+				runStart, runLine = lineStart, line
+			}
+			i = skipToNewline(input, i)
+			line++
+			continue
+		case '|':
+			// header or row line
+			if header {
+				lists = listColumns(input[i+1 : skipToNewline(input, i)-1])
+				header = false
+			}
+		default:
+			// table name line
+			header, lists = true, nil
+			tableCount++
+			if tableCount > 1 {
+				if runStart >= 0 {
+					segments = append(segments, segment{runStart, runLine})
+				} else {
+					segments = append(segments, segment{lineStart, line})
+				}
+			}
+		}
+		runStart = -1
+		i, line = skipTokens(input, i, line, lists)
+	}
+	return segments
+}
+
+// skipTokens skips the tokens of a line, starting at index i. Quoted
+// text may span more than one line. Lists tells, for each column,
+// whether it is a list column, see listColumns. It returns the index
+// after the newline that ends the line, and the number of that line.
+func skipTokens(input []byte, i, line int, lists []bool) (int, int) {
+	col := -1
+	for {
+		i = skipSpace(input, i)
+		if i >= len(input) {
+			return i, line
+		}
+		switch input[i] {
+		case '\n':
+			return i + 1, line + 1
+		case '|':
+			i++
+			col++
+		case '"':
+			i++
+			for i < len(input) && input[i] != '"' {
+				if input[i] == '\\' {
+					i++
+				} else if input[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i++
+		default:
+			if input[i] == '[' && col >= 0 && col < len(lists) && lists[col] {
+				i = skipList(input, i)
+				break
+			}
+			for i < len(input) && input[i] != '|' && input[i] != '\n' {
+				i++
+			}
+		}
+	}
+}
+
+// skipList skips a list, starting at the '[' at index i, up to the
+// next ']' that is not within quotes. A list does not span lines,
+// skipList stops at a newline. It returns the index after the list.
+func skipList(input []byte, i int) int {
+	quoted := false
+	for i++; i < len(input) && input[i] != '\n'; i++ {
+		switch {
+		case quoted && input[i] == '\\':
+			if i+1 < len(input) && input[i+1] != '\n' {
+				i++
+			}
+		case input[i] == '"':
+			quoted = !quoted
+		case input[i] == ']' && !quoted:
+			return i + 1
+		}
+	}
+	return i
+}
+
+// skipSpace skips the whitespace that the lexer skips between tokens.
+func skipSpace(input []byte, i int) int {
+	for i < len(input) && input[i] <= ' ' && input[i] != '\n' && input[i] != 0 {
+		i++
+	}
+	return i
+}
+
+// skipToNewline returns the index after the next newline.
+func skipToNewline(input []byte, i int) int {
+	for i < len(input) && input[i] != '\n' {
+		i++
+	}
+	return i + 1
+}
+
+// parseParallel parses input with up to o.Workers goroutines. It splits
+// the input into tables and parses each table in its own segment.
+// Errors are reported as if the input was parsed as a whole: the
+// lexers of the segments count lines and offsets from the beginning
+// of the input.
+// If a segment fails, all segments after it are irrelevant. If the
+// parser collects errors, the input is parsed again as a whole from the
+// first failed segment on, so that recovery and MaxErrors work across
+// segments.
+// Parsing stops when ctx is done, parseParallel returns ctx.Err()
+// in that case.
+func parseParallel(ctx context.Context, input []byte, o ParseOptions) (*Model, error) {
+	segments := splitTables(input)
+	type result struct {
+		model *Model
+		err   error
+	}
+	results := make([]result, len(segments))
+	// segments do not report progress, see ParseOptions.Progress
+	segmentOpts := o
+	segmentOpts.Progress = nil
+	parseSegment := func(k int, end int) (*Model, error) {
+		seg := segments[k]
+		lex := newSegmentLexer(input[:end], seg.start, seg.line)
+		p := newParser(lex, segmentOpts)
+		p.ctx = ctx
+		p.tableCount = k
+		return p.parse()
+	}
+	jobs := make(chan int, len(segments))
+	for k := range segments {
+		jobs <- k
+	}
+	close(jobs)
+	workers := o.Workers
+	if workers > len(segments) {
+		workers = len(segments)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				end := len(input)
+				if k+1 < len(segments) {
+					end = segments[k+1].start
+				}
+				model, err := parseSegment(k, end)
+				results[k] = result{model, err}
+			}
+		}()
+	}
+	wg.Wait()
+	// assemble the model
+	tables := []*Table{}
+	for k, res := range results {
+		if res.err != nil {
+			if !o.CollectErrors {
+				return nil, res.err
+			}
+			model, err := parseSegment(k, len(input))
+			if model == nil {
+				return nil, err
+			}
+			tables = append(tables, model.Tables...)
+			return &Model{Tables: tables, Comments: model.Comments}, err
+		}
+		tables = append(tables, res.model.Tables...)
+	}
+	if o.Progress != nil {
+		rows := 0
+		for _, table := range tables {
+			rows += table.NumRows()
+		}
+		o.Progress(int64(len(input)), rows)
+	}
+	comments := results[len(results)-1].model.Comments
+	return &Model{Tables: tables, Comments: comments}, nil
+}
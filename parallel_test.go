@@ -0,0 +1,112 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitTables(t *testing.T) {
+	testCases := []struct {
+		input string
+		exp   string
+	}{
+		{"", "[{0 1}]"},
+		{"a\n|x:i\n|1\n", "[{0 1}]"},
+		{"a\n|x:i\n|1\nb\n|y:s\n", "[{0 1} {10 4}]"},
+		{"a\n|x:i\n|1\n\n# b\n\nb\n", "[{0 1} {10 4}]"},
+		{"# a\na\nb\n  c\n", "[{0 1} {6 3} {8 4}]"},
+		{"a\n|x:s\n|\"1\nb\"\nc\n", "[{0 1} {14 5}]"},
+		{"a\n|x:s\n|\"\\\"\nb\"\nc\n", "[{0 1} {15 5}]"},
+		{"a\n|x:s\n|x\"\nb\n", "[{0 1} {11 4}]"},
+		{"\ufeffa\nb", "[{0 1} {5 2}]"},
+		{"a\n|x:[s]\n|[\"a|\"]\nb\n|y:s\n|\"c\"\n", "[{0 1} {17 4}]"},
+		{"a\n|x:[s]\n|[\"\\\"|\"]\nb\n", "[{0 1} {18 4}]"},
+		{"a\n|x:s|y:s\n|[x|\"1\nb\"\nc\n", "[{0 1} {21 5}]"},
+		{"[a\n|x:s\n|[\"\n[b\n", "[{0 1} {12 4}]"},
+	}
+	for _, testCase := range testCases {
+		act := fmt.Sprintf("%v", splitTables([]byte(testCase.input)))
+		assert.EqStrf(t, testCase.exp, act, "input %q", testCase.input)
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+	inputs := []string{
+		"",
+		"# only a comment\n",
+		"persons\n|id:i|name:s\n|1|\"joe\"\n|2|\"sue\"\n\n# cars\ncars\n|id:i\n|1\n\n# the end\n",
+		"\ufeffa\n|x:s\n|\"1\nb\"\nc\n|y:i\n|2",
+		"a\nb\nc\nd\n|x:i\n|1\n",
+		"a\n|x:i\n|1\nb\n|y:i\n|x\nc\n|z:i\n|y\n",
+		"a\n|x:i\n|1\nb\n|y:q\n|1\nc\n|z:i\n|2|3\n",
+		"|1\na\n|x:i\n",
+		"a\n|x:s\n|\"\\q\"\nb\n|y:s\n|\"z\"\n",
+	}
+	optss := []ParseOptions{
+		{},
+		{Positions: true},
+		{Strict: true},
+		{CollectErrors: true},
+		{CollectErrors: true, MaxErrors: 1},
+		{MaxTables: 2},
+		{MaxBytes: 20},
+		{Tables: []string{"b", "c"}},
+		{Columns: map[string][]string{"a": {"x"}, "b": {}}},
+	}
+	for _, input := range inputs {
+		for _, opts := range optss {
+			exp := stringifyParseResult(opts.ParseBytes([]byte(input)))
+			opts.Workers = 3
+			act := stringifyParseResult(opts.ParseBytes([]byte(input)))
+			assert.EqStrf(t, exp, act, "input %q, options %+v", input, opts)
+		}
+	}
+}
+
+func TestParseParallelFromFile(t *testing.T) {
+	input := ""
+	for i := 0; i < 20; i++ {
+		input += fmt.Sprintf("# table %d\ntable%d\n|id:i|name:s\n", i, i)
+		for j := 0; j < 100; j++ {
+			input += fmt.Sprintf("|%d|\"%d\"\n", j, j)
+		}
+	}
+	input += "broken\n|id:i\n|x\n"
+	file, err := ioutil.TempFile("", "tdat")
+	assert.Truef(t, err == nil, "err was %s", err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	file.Close()
+	opts := ParseOptions{Workers: 4, CollectErrors: true}
+	model, err := opts.ParseFromFile(file.Name())
+	assert.EqStr(t, "line 2063, pos 2: cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax", err.Error())
+	assert.EqInt(t, 21, len(model.Tables))
+	for i, table := range model.Tables[:20] {
+		assert.EqStr(t, fmt.Sprintf("table%d", i), table.Name)
+		assert.EqStr(t, fmt.Sprintf("[table %d]", i), fmt.Sprintf("%v", table.Comments))
+		assert.EqInt(t, 100, len(table.Rows))
+	}
+}
+
+func stringifyParseResult(model *Model, err error) string {
+	s := ""
+	if model != nil {
+		for _, table := range model.Tables {
+			s += stringifyTable(table)
+			s += fmt.Sprintf("  span %s comments %q\n", table.Span(), table.Comments)
+			for i, row := range table.Rows {
+				s += fmt.Sprintf("  row span %s comments %q\n", table.RowSpan(i), row.Comments)
+			}
+		}
+		s += fmt.Sprintf("comments %q\n", model.Comments)
+	}
+	if err != nil {
+		s += err.Error()
+	}
+	return strings.TrimSpace(s)
+}
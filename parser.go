@@ -1,9 +1,13 @@
 package tdat
 
 import (
-	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -12,57 +16,218 @@ import (
 
 // ParseFromString is like ParseFromRuneReader but reads input from a string.
 func ParseFromString(input string) (*Model, error) {
-	runeReader := strings.NewReader(input)
-	return ParseFromRuneReader(runeReader)
+	return ParseOptions{}.ParseFromString(input)
 }
 
 // ParseFromFile is like ParseFromRuneReader but reads input from a file.
 func ParseFromFile(name string) (*Model, error) {
-	file, err := os.Open(name)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	runeReader := bufio.NewReader(file)
-	return ParseFromRuneReader(runeReader)
+	return ParseOptions{}.ParseFromFile(name)
 }
 
 // ParseFromReader is like ParseFromRuneReader but reads input from a reader.
 func ParseFromReader(reader io.Reader) (*Model, error) {
-	runeReader := bufio.NewReader(reader)
-	return ParseFromRuneReader(runeReader)
+	return ParseOptions{}.ParseFromReader(reader)
 }
 
 // ParseFromRuneReader parses a model from an io.RuneReader.
 // It returns any error that occurs while parsing the input.
 func ParseFromRuneReader(reader io.RuneReader) (*Model, error) {
-	p := newParser(newLexer(reader))
+	return ParseOptions{}.ParseFromRuneReader(reader)
+}
+
+// ParseWithContext is like ParseFromReader but stops parsing when ctx
+// is done. In that case, it returns ctx.Err().
+func ParseWithContext(ctx context.Context, reader io.Reader) (*Model, error) {
+	return ParseOptions{}.ParseWithContext(ctx, reader)
+}
+
+// ParseBytes is like ParseFromRuneReader but reads input from a byte slice.
+// It is the fastest way to parse a model: the parser scans the input
+// in place, without copying it. ParseBytes does not modify input.
+func ParseBytes(input []byte) (*Model, error) {
+	return ParseOptions{}.ParseBytes(input)
+}
+
+// ParseOptions control how input is parsed.
+// The zero value holds the default options.
+type ParseOptions struct {
+
+	// CollectErrors lets the parser continue after an error. The parser
+	// skips the rest of the line where the error occurred and goes on
+	// with the next line. Rows with errors are dropped, as well as all rows
+	// of a table with an erroneous header. If errors occurred, the parser
+	// returns the partial model and an ErrorList that holds all errors.
+	CollectErrors bool
+
+	// MaxErrors stops a parser that collects errors after that many errors.
+	// If MaxErrors <= 0, there is no limit.
+	MaxErrors int
+
+	// Strict lets the parser accept only values that conform to
+	// the grammar in rfc.txt, section 3. String values must be quoted,
+	// all other values must not be quoted.
+	// If Strict is false, the parser is lenient: it accepts everything
+	// that the Go strconv and time packages can parse, for example "007"
+	// or "0x1p-2" for numbers, "T" or "1" for booleans and unquoted
+	// strings.
+	Strict bool
+
+	// Positions lets the parser record where tables, columns, rows
+	// and values are located in the input, see Table.Span,
+	// Table.ColumnSpan, Table.RowSpan and Table.ValueSpan.
+	Positions bool
+
+	// Workers lets ParseFromFile, ParseBytes and ParseWithContext parse
+	// the tables of the input in parallel, using up to Workers goroutines.
+	// The resulting model and errors are the same as without Workers.
+	// ParseFromFile and ParseWithContext read the whole input into memory
+	// before parsing it.
+	// If Workers <= 1, the input is parsed by one goroutine.
+	Workers int
+
+	// Tables selects the tables to parse, by name. Other tables are
+	// skipped: their rows are scanned, but not parsed, and they are not
+	// part of the model. If Tables is empty, all tables are parsed.
+	Tables []string
+
+	// Columns selects the columns to parse, by table name and column
+	// name. Values of other columns are not converted, and the model
+	// holds only the selected columns and their values. Rows must still
+	// have as many values as the table has columns. If a table is not
+	// in Columns, all its columns are parsed.
+	Columns map[string][]string
+
+	// Progress, if not nil, is called periodically while parsing, and
+	// once at the end, with the number of bytes and rows parsed so far.
+	// If Workers > 1, Progress is called only at the end.
+	Progress func(bytes int64, rows int)
+
+	// Columnar lets the parser create tables in columnar form,
+	// see Table.IsColumnar. Rows and values have no spans then.
+	Columnar bool
+
+	// MaxBytes limits the size of the input, in bytes.
+	MaxBytes int64
+
+	// MaxTables limits the number of tables.
+	MaxTables int
+
+	// MaxColumns limits the number of columns of a table.
+	MaxColumns int
+
+	// MaxRowsPerTable limits the number of rows of a table.
+	MaxRowsPerTable int
+
+	// MaxStringLen limits the length of table names, column
+	// definitions and values, in runes.
+	MaxStringLen int
+
+	// If a limit is exceeded, the parser fails with a ParseError whose
+	// Cause is a *LimitExceededError. The parser stops at this error,
+	// even if it collects errors. Limits <= 0 mean no limit.
+}
+
+// ParseFromString is like the package-level function ParseFromString
+// but uses the options in o.
+func (o ParseOptions) ParseFromString(input string) (*Model, error) {
+	return o.ParseFromReader(strings.NewReader(input))
+}
+
+// ParseFromFile is like the package-level function ParseFromFile
+// but uses the options in o.
+func (o ParseOptions) ParseFromFile(name string) (*Model, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if o.Workers > 1 {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		// an oversized file fails early when it is streamed
+		if o.MaxBytes <= 0 || info.Size() <= o.MaxBytes {
+			input, err := ioutil.ReadAll(file)
+			if err != nil {
+				return nil, err
+			}
+			return parseParallel(context.Background(), input, o)
+		}
+	}
+	return o.ParseFromReader(file)
+}
+
+// ParseFromReader is like the package-level function ParseFromReader
+// but uses the options in o.
+// The parser buffers the input, so reader need not be buffered.
+func (o ParseOptions) ParseFromReader(reader io.Reader) (*Model, error) {
+	p := newParser(newLexer(reader), o)
 	return p.parse()
 }
 
-// ----------------------------------------------
+// ParseFromRuneReader is like the package-level function
+// ParseFromRuneReader but uses the options in o.
+func (o ParseOptions) ParseFromRuneReader(reader io.RuneReader) (*Model, error) {
+	if r, ok := reader.(io.Reader); ok {
+		return o.ParseFromReader(r)
+	}
+	return o.ParseFromReader(runeReader{reader})
+}
 
-type tokenError struct {
-	tok *token
-	err error
+// ParseWithContext is like ParseFromReader but stops parsing when ctx
+// is done. In that case, it returns ctx.Err().
+func (o ParseOptions) ParseWithContext(ctx context.Context, reader io.Reader) (*Model, error) {
+	if o.Workers > 1 {
+		if o.MaxBytes > 0 {
+			// an oversized input fails when it is parsed
+			reader = io.LimitReader(reader, o.MaxBytes+1)
+		}
+		input, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return parseParallel(ctx, input, o)
+	}
+	p := newParser(newLexer(reader), o)
+	p.ctx = ctx
+	return p.parse()
 }
 
-func (e tokenError) Error() string {
-	return fmt.Sprintf("line %d, pos %d: %s", e.tok.line, e.tok.pos, e.err)
+// ParseBytes is like the package-level function ParseBytes
+// but uses the options in o.
+func (o ParseOptions) ParseBytes(input []byte) (*Model, error) {
+	if o.Workers > 1 {
+		return parseParallel(context.Background(), input, o)
+	}
+	p := newParser(newBytesLexer(input), o)
+	return p.parse()
 }
 
+// ----------------------------------------------
+
 /*
 	TOKEN TYPES
 	-----------
 	Text
 	Separator
 	NewLine
+	Comment
 	EOF
 
 
 	PARSER STATES
 	-------------
 
+	Comment lines are collected and attached to the next table,
+	column or row that is created. Comments that are not followed
+	by a table, column or row are attached to the model.
+
+	'table complete' means that the name and header of the current table
+	have been parsed, 'row complete' means that the current data row has
+	been parsed. The parser reports both as events (see parser.next).
+	A pushed back token is processed again in the next state.
+
 	[Start]
 		Text / add new table
 			---> [AfterName]
@@ -70,37 +235,41 @@ func (e tokenError) Error() string {
 			---> [AfterDataSeparator]
 		NewLine
 			---> [Start]
+		Comment / collect comment
+			---> [Start]
 		EOF
 			---> [End]
 
 	[AfterName]
 		NewLine
 			---> [AfterNameLine]
-		EOF
+		EOF / table complete
 			---> [End]
 
 	[AfterNameLine]
-		Text / add new table
-			---> [AfterName]
+		Text / table complete / push back text
+			---> [Start]
 		Separator
 			---> [AfterHeaderSeparator]
 		NewLine
 			---> [AfterNameLine]
-		EOF
+		Comment / collect comment
+			---> [AfterNameLine]
+		EOF / table complete
 			---> [End]
 
 	[AfterHeaderSeparator]
 		Text / add new column to table
 			---> [AfterHeaderText]
-		EOF
+		EOF / table complete
 			--> [End]
 
 	[AfterHeaderText]
 		Separator
 			---> [AfterHeaderSeparator]
-		NewLine
+		NewLine / table complete
 			---> [Start]
-		EOF
+		EOF / table complete
 			--> [End]
 
 	[AfterDataSeparator]
@@ -108,17 +277,17 @@ func (e tokenError) Error() string {
 			---> [AfterDataText]
 		Separator / add null value to last row, defer type from i-th column
 			---> [AfterDataSeparator]
-		NewLine / add null value to last row, defer type from i-th column / check last_row_width == header_width
+		NewLine / add null value to last row, defer type from i-th column / check last_row_width == header_width / row complete
 			---> [Start]
-		EOF / check last_row_width == header_width
+		EOF / check last_row_width == header_width / row complete
 			--> [End]
 
 	[AfterDataText]
 		Separator / check last_row_width < header width
 			---> [AfterDataSeparator]
-		NewLine / check last_row_width == header_width
+		NewLine / check last_row_width == header_width / row complete
 			---> [Start]
-		EOF / check last_row_width == header_width
+		EOF / check last_row_width == header_width / row complete
 			---> [End]
 
 	[End]
@@ -160,24 +329,175 @@ const (
 	endState
 )
 
+// A parserEvent is reported by the parser whenever it has completed
+// a table header or a data row, or has reached the end of input.
+type parserEvent int
+
+const (
+	noEvent parserEvent = iota
+	tableEvent
+	rowEvent
+	endEvent
+)
+
 type parser struct {
-	lex    *lexer
-	state  parserState
-	tables []*Table
-	table  *Table
+	lex        *lexer
+	opts       ParseOptions
+	ctx        context.Context
+	rowCount   int
+	state      parserState
+	tableCount int
+	table      *Table
+	skipTable  bool
+	columns    []*Column
+	keep       []bool
+	row        *Row
+	values     []Value
+	spare      *Row
+	rowIndex   int
+	skipRows   bool
+	skipLists  []bool
+	skipCount  int
+	checkDue   bool
+	cellStart  Position
+	rowSpan    Span
+	valueSpans []Span
+	comments   []string
+	tok        *token
+	event      parserEvent
+	errors     ErrorList
 }
 
-func newParser(lex *lexer) *parser {
-	return &parser{lex, startState, []*Table{}, nil}
+func newParser(lex *lexer, opts ParseOptions) *parser {
+	lex.strict = opts.Strict
+	lex.maxBytes = opts.MaxBytes
+	lex.maxStringLen = opts.MaxStringLen
+	return &parser{lex: lex, opts: opts, state: startState, rowIndex: -1}
 }
 
+// parse parses all tables and rows and returns them as a model.
+// If the parser collects errors, parse returns the partial model
+// and all errors.
 func (p *parser) parse() (*Model, error) {
+	tables := []*Table{}
 	for {
-		tok, err := p.lex.next()
+		event, err := p.next()
 		if err != nil {
+			if _, ok := err.(ErrorList); ok {
+				return &Model{Tables: tables}, err
+			}
 			return nil, err
 		}
+		switch event {
+		case tableEvent:
+			tables = append(tables, p.table)
+			if err := p.done(); err != nil {
+				return nil, err
+			}
+		case rowEvent:
+			p.addRowSpans()
+			if p.opts.Columnar {
+				// the row is copied, it can be reused for the next row
+				p.table.appendRow(p.row)
+				p.spare = p.row
+			} else {
+				p.table.Rows = append(p.table.Rows, p.row)
+			}
+			p.rowCount++
+			if p.rowCount%progressInterval == 0 {
+				if err := p.checkpoint(); err != nil {
+					return nil, err
+				}
+			}
+		case endEvent:
+			if err := p.checkpoint(); err != nil {
+				return nil, err
+			}
+			model := &Model{Tables: tables, Comments: p.takeComments()}
+			if len(p.errors) > 0 {
+				return model, p.errors
+			}
+			return model, nil
+		}
+	}
+}
+
+// progressInterval is the number of rows after which the parser
+// and the renderer check their context and report progress. Rows
+// that the parser skips count as well.
+const progressInterval = 1024
+
+// checkpoint returns the error of the parser's context, if it is done,
+// and reports progress.
+func (p *parser) checkpoint() error {
+	if err := p.done(); err != nil {
+		return err
+	}
+	if p.opts.Progress != nil {
+		p.opts.Progress(p.lex.offset, p.rowCount)
+	}
+	return nil
+}
+
+// done returns the error of the parser's context, if it is done.
+func (p *parser) done() error {
+	if p.ctx != nil {
+		select {
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		default:
+		}
+	}
+	return nil
+}
+
+// next consumes tokens until the parser has completed a table header
+// (tableEvent), a data row (rowEvent) or has reached the end of
+// input (endEvent). The completed table is p.table, the completed
+// row is p.row. Completed rows are not added to p.table.
+func (p *parser) next() (parserEvent, error) {
+	for {
+		if p.state == endState {
+			return endEvent, nil
+		}
+		if p.checkDue {
+			// skipped tables and rows raise no events
+			p.checkDue = false
+			if err := p.checkpoint(); err != nil {
+				return noEvent, err
+			}
+		}
+		tok := p.tok
+		p.tok = nil
+		if tok == nil {
+			p.lex.lists = false
+			if p.state == afterDataSeparatorState {
+				n := len(p.row.Values)
+				p.lex.lists = n < len(p.columns) && p.columns[n].Type == ListValue
+			}
+			var err error
+			tok, err = p.lex.next()
+			if err != nil {
+				e, ok := err.(*ParseError)
+				if !ok {
+					return noEvent, err
+				}
+				p.setErrorContext(e)
+				if !p.opts.CollectErrors || isLimitError(e) {
+					return noEvent, e
+				}
+				if p.collectError(e) {
+					return noEvent, p.errors
+				}
+				if event := p.complete(p.recover(nil)); event != noEvent {
+					return event, nil
+				}
+				continue
+			}
+		}
 		//fmt.Printf("%-20s %s\n", p.state, tok)
+		p.event = noEvent
+		var err error
 		switch p.state {
 		case startState:
 			err = p.forStart(tok)
@@ -193,18 +513,187 @@ func (p *parser) parse() (*Model, error) {
 			err = p.forAfterDataSeparator(tok)
 		case afterDataTextState:
 			err = p.forAfterDataText(tok)
-		case endState:
-			panic("cannot parse beyond end")
 		default:
 			panic("invalid parser state")
 		}
 		if err != nil {
-			return nil, tokenError{tok, err}
+			e := &ParseError{Line: tok.line, Pos: tok.pos, Cause: err}
+			p.setErrorContext(e)
+			if !p.opts.CollectErrors || isLimitError(e) {
+				return noEvent, e
+			}
+			if p.collectError(e) {
+				return noEvent, p.errors
+			}
+			p.event = p.recover(tok)
 		}
-		if p.state == endState {
-			return &Model{p.tables}, nil
+		p.event = p.complete(p.event)
+		if p.event != noEvent {
+			return p.event, nil
+		}
+	}
+}
+
+// setErrorContext sets table name, column name and row index
+// of a ParseError, depending on the current parser state.
+func (p *parser) setErrorContext(e *ParseError) {
+	e.RowIndex = -1
+	if p.table == nil {
+		return
+	}
+	e.TableName = p.table.Name
+	switch p.state {
+	case afterDataSeparatorState, afterDataTextState:
+		e.RowIndex = p.rowIndex
+		colIndex := len(p.row.Values)
+		if p.state == afterDataSeparatorState && colIndex < len(p.columns) {
+			e.ColumnName = p.columns[colIndex].Name
+		}
+	}
+}
+
+// complete finishes a table or row that the parser has completed
+// with event. It projects the columns of a table and the values of
+// a row to the columns selected by ParseOptions.Columns. Events of
+// tables that are not selected by ParseOptions.Tables are dropped,
+// complete returns noEvent for them.
+func (p *parser) complete(event parserEvent) parserEvent {
+	switch event {
+	case tableEvent:
+		if p.skipTable {
+			return noEvent
+		}
+		p.keep = nil
+		p.table.Columns = p.columns
+		names, ok := p.opts.Columns[p.table.Name]
+		if ok {
+			p.keep = make([]bool, len(p.columns))
+			p.table.Columns = []*Column{}
+			for i, column := range p.columns {
+				for _, name := range names {
+					if column.Name == name {
+						p.keep[i] = true
+						p.table.Columns = append(p.table.Columns, column)
+						break
+					}
+				}
+			}
+		}
+		if s := p.table.spans; s != nil && p.keep != nil {
+			columns := s.columns[:0]
+			for i, span := range s.columns {
+				if p.keep[i] {
+					columns = append(columns, span)
+				}
+			}
+			s.columns = columns
+		}
+		if p.opts.Columnar {
+			p.table.initColumns()
+		}
+	case rowEvent:
+		if p.keep != nil {
+			values := p.row.Values[:0]
+			for i, value := range p.row.Values {
+				if p.keep[i] {
+					values = append(values, value)
+				}
+			}
+			p.row.Values = values
+		}
+	}
+	return event
+}
+
+// selected reports whether a table is selected by ParseOptions.Tables.
+func (p *parser) selected(name string) bool {
+	if len(p.opts.Tables) == 0 {
+		return true
+	}
+	for _, s := range p.opts.Tables {
+		if s == name {
+			return true
 		}
 	}
+	return false
+}
+
+// collectError adds an error to the list of errors. It returns true if
+// the maximum number of errors is reached.
+func (p *parser) collectError(e *ParseError) bool {
+	p.errors = append(p.errors, e)
+	return p.opts.MaxErrors > 0 && len(p.errors) >= p.opts.MaxErrors
+}
+
+// recover brings the parser back into a valid state after an error
+// occurred at token tok, or in the lexer if tok is nil.
+// It skips the rest of the current line. If the error occurred
+// in a header, the table is complete and all its rows will
+// be skipped.
+func (p *parser) recover(tok *token) parserEvent {
+	if tok == nil || tok.ttype == textToken || tok.ttype == separatorToken {
+		p.lex.skipLine()
+	}
+	event := noEvent
+	switch p.state {
+	case afterNameState, afterNameLineState:
+		p.state = afterNameLineState
+	case afterHeaderSeparatorState, afterHeaderTextState:
+		event = tableEvent
+		p.skipRows = true
+		p.state = startState
+	default:
+		p.row = nil
+		p.state = startState
+	}
+	if tok != nil && tok.ttype == eofToken {
+		p.state = endState
+	}
+	return event
+}
+
+// span returns the span of tok.
+func (p *parser) span(tok *token) Span {
+	return Span{Position{tok.line, tok.pos}, tok.end}
+}
+
+// addValueSpan records the span of the next value of the current
+// row, if the parser records positions.
+func (p *parser) addValueSpan(span Span) {
+	if p.opts.Positions {
+		p.valueSpans = append(p.valueSpans, span)
+	}
+}
+
+// addRowSpans records the spans of the completed row, and of its
+// values, in the spans of the table, if the parser records positions.
+// Like the values, the spans are projected to the selected columns.
+func (p *parser) addRowSpans() {
+	s := p.table.spans
+	if s == nil {
+		return
+	}
+	s.rows = append(s.rows, p.rowSpan)
+	for i, span := range p.valueSpans {
+		if p.keep == nil || p.keep[i] {
+			s.values = append(s.values, span)
+		}
+	}
+	s.table.End = p.rowSpan.End
+}
+
+// takeComments returns the comments collected so far and
+// clears them.
+func (p *parser) takeComments() []string {
+	comments := p.comments
+	p.comments = nil
+	return comments
+}
+
+// unread pushes back a token, it will be the next token
+// processed by the parser.
+func (p *parser) unread(tok *token) {
+	p.tok = tok
 }
 
 func (p *parser) forStart(tok *token) error {
@@ -214,31 +703,77 @@ func (p *parser) forStart(tok *token) error {
 				---> [AfterName]
 			Separator / check if we have table / create new row
 				---> [AfterDataSeparator]
+			Separator / table not selected: skip line
+				---> [Start]
 			NewLine
 				---> [Start]
+			Comment / collect comment
+				---> [Start]
 			EOF
 				---> [End]
 	*/
 	switch tok.ttype {
 	case textToken:
-		columns := make([]*Column, 0, 10)
-		rows := make([]*Row, 0, 1000)
-		p.table = &Table{tok.text, columns, rows}
-		p.tables = append(p.tables, p.table)
+		p.tableCount++
+		if p.opts.MaxTables > 0 && p.tableCount > p.opts.MaxTables {
+			return &LimitExceededError{"MaxTables", int64(p.opts.MaxTables)}
+		}
+		p.table = &Table{Name: string(tok.text), Columns: []*Column{}, Rows: []*Row{}, Comments: p.takeComments()}
+		if p.opts.Positions {
+			p.table.spans = &tableSpans{table: p.span(tok)}
+		}
+		p.columns = []*Column{}
+		p.keep = nil
+		p.spare = nil
+		p.skipTable = !p.selected(p.table.Name)
+		p.checkDue = p.skipTable
+		p.rowIndex = -1
+		p.skipRows = false
+		p.skipLists = nil
 		p.state = afterNameState
 		return nil
 	case separatorToken:
 		if p.table == nil {
 			return fmt.Errorf("unexpected separator")
 		}
-		values := make([]*Value, 0, 20)
-		row := &Row{values}
-		p.table.Rows = append(p.table.Rows, row)
+		if p.skipRows || p.skipTable {
+			p.comments = nil
+			if p.skipRows {
+				p.lex.skipLine()
+			} else {
+				p.lex.skipRow(p.skipLists)
+			}
+			p.skipCount++
+			if p.skipCount%progressInterval == 0 {
+				p.checkDue = true
+			}
+			return nil
+		}
+		if p.opts.MaxRowsPerTable > 0 && p.rowIndex+1 >= p.opts.MaxRowsPerTable {
+			return &LimitExceededError{"MaxRowsPerTable", int64(p.opts.MaxRowsPerTable)}
+		}
+		if p.spare != nil {
+			p.row = p.spare
+			p.row.Values = p.row.Values[:0]
+			p.row.Comments = p.takeComments()
+		} else {
+			// the values of a row are allocated in one block
+			values := make([]*Value, 0, len(p.columns))
+			p.values = make([]Value, len(p.columns))
+			p.row = &Row{Values: values, Comments: p.takeComments()}
+		}
+		p.rowSpan = p.span(tok)
+		p.valueSpans = p.valueSpans[:0]
+		p.cellStart = tok.end
+		p.rowIndex++
 		p.state = afterDataSeparatorState
 		return nil
 	case newlineToken:
 		p.state = startState
 		return nil
+	case commentToken:
+		p.comments = append(p.comments, string(tok.text))
+		return nil
 	case eofToken:
 		p.state = endState
 		return nil
@@ -252,7 +787,7 @@ func (p *parser) forAfterName(tok *token) error {
 		[AfterName]
 			NewLine
 				---> [AfterNameLine]
-			EOF
+			EOF / table complete
 				---> [End]
 	*/
 	switch tok.ttype {
@@ -264,6 +799,7 @@ func (p *parser) forAfterName(tok *token) error {
 		p.state = afterNameLineState
 		return nil
 	case eofToken:
+		p.event = tableEvent
 		p.state = endState
 		return nil
 	default:
@@ -274,28 +810,45 @@ func (p *parser) forAfterName(tok *token) error {
 func (p *parser) forAfterNameLine(tok *token) error {
 	/*
 		[AfterNameLine]
-			Text / add new table
-				---> [AfterName]
+			Text / table complete / push back text
+				---> [Start]
 			Separator
 				---> [AfterHeaderSeparator]
+			Separator / table not selected: skip line
+				---> [Start]
 			NewLine
 				---> [AfterNameLine]
-			EOF
+			Comment / collect comment
+				---> [AfterNameLine]
+			EOF / table complete
 				---> [End]
 	*/
 	switch tok.ttype {
 	case textToken:
-		p.table = &Table{tok.text, []*Column{}, []*Row{}}
-		p.tables = append(p.tables, p.table)
-		p.state = afterNameState
+		// the table has no header, it is complete now. The new
+		// table is added by [Start], when it reads the text again.
+		p.event = tableEvent
+		p.unread(tok)
+		p.state = startState
 		return nil
 	case separatorToken:
+		if p.skipTable {
+			// the header of a table that is not selected
+			p.comments = nil
+			p.skipLists = p.lex.skipHeader()
+			p.state = startState
+			return nil
+		}
 		p.state = afterHeaderSeparatorState
 		return nil
 	case newlineToken:
 		p.state = afterNameLineState
 		return nil
+	case commentToken:
+		p.comments = append(p.comments, string(tok.text))
+		return nil
 	case eofToken:
+		p.event = tableEvent
 		p.state = endState
 		return nil
 	default:
@@ -308,16 +861,24 @@ func (p *parser) forAfterHeaderSeparator(tok *token) error {
 		[AfterHeaderSeparator]
 			Text / add new column to table
 				---> [AfterHeaderText]
-			EOF
+			EOF / table complete
 				--> [End]
 	*/
 	switch tok.ttype {
 	case textToken:
-		column, err := p.parseColumn(tok.text)
+		if p.opts.MaxColumns > 0 && len(p.columns) >= p.opts.MaxColumns {
+			return &LimitExceededError{"MaxColumns", int64(p.opts.MaxColumns)}
+		}
+		column, err := p.parseColumn(string(tok.text))
 		if err != nil {
 			return err
 		}
-		p.table.Columns = append(p.table.Columns, column)
+		column.Comments = p.takeComments()
+		if s := p.table.spans; s != nil {
+			s.columns = append(s.columns, p.span(tok))
+			s.table.End = tok.end
+		}
+		p.columns = append(p.columns, column)
 		p.state = afterHeaderTextState
 		return nil
 	case separatorToken:
@@ -325,6 +886,7 @@ func (p *parser) forAfterHeaderSeparator(tok *token) error {
 	case newlineToken:
 		return fmt.Errorf("unexpected end of line")
 	case eofToken:
+		p.event = tableEvent
 		p.state = endState
 		return nil
 	default:
@@ -337,9 +899,9 @@ func (p *parser) forAfterHeaderText(tok *token) error {
 		[AfterHeaderText]
 			Separator
 				---> [AfterHeaderSeparator]
-			NewLine
+			NewLine / table complete
 				---> [Start]
-			EOF
+			EOF / table complete
 				--> [End]
 	*/
 	switch tok.ttype {
@@ -349,9 +911,11 @@ func (p *parser) forAfterHeaderText(tok *token) error {
 		p.state = afterHeaderSeparatorState
 		return nil
 	case newlineToken:
+		p.event = tableEvent
 		p.state = startState
 		return nil
 	case eofToken:
+		p.event = tableEvent
 		p.state = endState
 		return nil
 	default:
@@ -366,13 +930,13 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 				---> [AfterDataText]
 			Separator / add null value to last row, defer type from i-th column
 				---> [AfterDataSeparator]
-			NewLine / add null value to last row, defer type from i-th column / check last_row_width == header_width
+			NewLine / add null value to last row, defer type from i-th column / check last_row_width == header_width / row complete
 				---> [Start]
-			EOF / check last_row_width == header_width
+			EOF / check last_row_width == header_width / row complete
 				--> [End]
 	*/
-	row := p.table.Rows[len(p.table.Rows)-1]
-	columns := p.table.Columns
+	row := p.row
+	columns := p.columns
 	switch tok.ttype {
 	case textToken:
 		// find type of i-th column
@@ -381,12 +945,24 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 			return fmt.Errorf("too many data values")
 		}
 		colType := columns[colIndex].Type
-		// parse value
-		value, err := p.parseValue(colType, tok.text)
-		if err != nil {
-			return err
+		// check quoting
+		if p.opts.Strict && tok.quoted != (colType == StringValue) {
+			if tok.quoted {
+				return fmt.Errorf("value must not be quoted")
+			}
+			return fmt.Errorf("string value must be quoted")
+		}
+		// parse value, unless the column is not selected
+		value := &p.values[colIndex]
+		if p.keep == nil || p.keep[colIndex] {
+			err := p.parseValue(value, columns[colIndex], tok.text)
+			if err != nil {
+				return err
+			}
 		}
 		// append value to last row
+		p.addValueSpan(p.span(tok))
+		p.rowSpan.End = tok.end
 		row.Values = append(row.Values, value)
 		p.state = afterDataTextState
 		return nil
@@ -398,8 +974,12 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 		}
 		colType := columns[colIndex].Type
 		// append null value to last row
-		value := &Value{Type: colType, Null: true}
+		value := &p.values[colIndex]
+		*value = Value{Type: colType, Null: true}
+		p.addValueSpan(Span{p.cellStart, p.cellStart})
 		row.Values = append(row.Values, value)
+		p.rowSpan.End = tok.end
+		p.cellStart = tok.end
 		p.state = afterDataSeparatorState
 		return nil
 	case newlineToken:
@@ -410,7 +990,9 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 		}
 		colType := columns[colIndex].Type
 		// append null value to last row
-		value := &Value{Type: colType, Null: true}
+		value := &p.values[colIndex]
+		*value = Value{Type: colType, Null: true}
+		p.addValueSpan(Span{p.cellStart, p.cellStart})
 		row.Values = append(row.Values, value)
 		// check row_width == header_width
 		if len(row.Values) < len(columns) {
@@ -419,6 +1001,7 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 		if len(row.Values) > len(columns) {
 			return fmt.Errorf("too many data values")
 		}
+		p.event = rowEvent
 		p.state = startState
 		return nil
 	case eofToken:
@@ -429,6 +1012,7 @@ func (p *parser) forAfterDataSeparator(tok *token) error {
 		if len(row.Values) > len(columns) {
 			return fmt.Errorf("too many data values")
 		}
+		p.event = rowEvent
 		p.state = endState
 		return nil
 	default:
@@ -441,13 +1025,13 @@ func (p *parser) forAfterDataText(tok *token) error {
 		[AfterDataText]
 			Separator / check last_row_width < header width
 				---> [AfterDataSeparator]
-			NewLine / check last_row_width == header_width
+			NewLine / check last_row_width == header_width / row complete
 				---> [Start]
-			EOF / check last_row_width == header_width
+			EOF / check last_row_width == header_width / row complete
 				---> [End]
 	*/
-	row := p.table.Rows[len(p.table.Rows)-1]
-	columns := p.table.Columns
+	row := p.row
+	columns := p.columns
 	rowWidth := len(row.Values)
 	headerWidth := len(columns)
 	switch tok.ttype {
@@ -457,6 +1041,8 @@ func (p *parser) forAfterDataText(tok *token) error {
 		if rowWidth >= headerWidth {
 			return fmt.Errorf("too many data values")
 		}
+		p.rowSpan.End = tok.end
+		p.cellStart = tok.end
 		p.state = afterDataSeparatorState
 		return nil
 	case newlineToken:
@@ -466,6 +1052,7 @@ func (p *parser) forAfterDataText(tok *token) error {
 		if rowWidth < headerWidth {
 			return fmt.Errorf("too few data values")
 		}
+		p.event = rowEvent
 		p.state = startState
 		return nil
 	case eofToken:
@@ -475,6 +1062,7 @@ func (p *parser) forAfterDataText(tok *token) error {
 		if rowWidth < headerWidth {
 			return fmt.Errorf("too few data values")
 		}
+		p.event = rowEvent
 		p.state = endState
 		return nil
 	default:
@@ -482,55 +1070,403 @@ func (p *parser) forAfterDataText(tok *token) error {
 	}
 }
 
+// parseColumn parses a column definition, see rfc.txt, section 2.
+// The type is a type code, or an enum with its symbols, or a list
+// of one of these, in brackets.
 func (p *parser) parseColumn(text string) (*Column, error) {
-	n := len(text)
-	if n < 3 {
-		return nil, fmt.Errorf("invalid column definition")
+	// find the colon between name and type
+	var i int
+	switch {
+	case strings.HasSuffix(text, ")]"):
+		i = strings.LastIndex(text, ":[e(")
+	case strings.HasSuffix(text, ")"):
+		i = strings.LastIndex(text, ":e(")
+	case strings.HasSuffix(text, "]"):
+		i = len(text) - 4
+	default:
+		i = len(text) - 2
 	}
-	if text[n-2] != ':' {
+	if i < 1 || text[i] != ':' {
 		return nil, fmt.Errorf("invalid column definition")
 	}
-	typeChar := text[n-1]
-	name := text[:n-2]
-	switch typeChar {
-	case 'i', 'f', 'b', 's', 't':
-		return &Column{name, ValueType(typeChar)}, nil
-	default:
-		return nil, fmt.Errorf("invalid column type")
+	column := &Column{Name: text[:i]}
+	def := text[i+1:]
+	list := strings.HasSuffix(def, "]")
+	if list {
+		if def[0] != '[' {
+			return nil, fmt.Errorf("invalid column definition")
+		}
+		def = def[1 : len(def)-1]
+	}
+	var typeChar ValueType
+	if strings.HasPrefix(def, "e(") {
+		symbols := strings.Split(def[2:len(def)-1], ",")
+		err := validateSymbols(symbols)
+		if err != nil {
+			return nil, fmt.Errorf("invalid column definition: %s", err)
+		}
+		typeChar, column.Symbols = EnumValue, symbols
+	} else {
+		typeChar = ValueType(def[0])
+		if !typeChar.IsValid() || typeChar == ListValue {
+			return nil, fmt.Errorf("invalid column type")
+		}
+		if typeChar == EnumValue {
+			return nil, fmt.Errorf("invalid column definition: enum has no symbols")
+		}
+	}
+	if list {
+		column.Type, column.ElemType = ListValue, typeChar
+	} else {
+		column.Type = typeChar
 	}
+	return column, nil
 }
 
-func (p *parser) parseValue(colType ValueType, text string) (*Value, error) {
-	v := &Value{Type: colType, Null: false}
+// parseValue parses text as a value of the type of column into v.
+// Common number and time formats are parsed directly from the
+// bytes of text, everything else goes through the strconv and
+// time packages.
+func (p *parser) parseValue(v *Value, column *Column, text []byte) error {
+	colType := column.Type
+	v.Type = colType
+	v.Null = false
 	switch colType {
 	case IntValue:
-		x, err := strconv.ParseInt(text, 10, 64)
+		if p.opts.Strict && !isInteger(text) {
+			return fmt.Errorf("cannot parse as int: invalid syntax")
+		}
+		if x, ok := parseSimpleInt(text); ok {
+			v.AsInt = x
+			return nil
+		}
+		x, err := parseInt(string(text))
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse as int: %s", err)
+			return fmt.Errorf("cannot parse as int: %s", err)
 		}
 		v.AsInt = x
 	case FloatValue:
-		x, err := strconv.ParseFloat(text, 64)
+		if p.opts.Strict && !isFloat(text) {
+			return fmt.Errorf("cannot parse as float: invalid syntax")
+		}
+		if x, ok := parseSimpleFloat(text); ok {
+			v.AsFloat = x
+			return nil
+		}
+		x, err := strconv.ParseFloat(string(text), 64)
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse as float: %s", err)
+			return fmt.Errorf("cannot parse as float: %s", err)
 		}
 		v.AsFloat = x
 	case BoolValue:
-		x, err := strconv.ParseBool(text)
+		if p.opts.Strict && !isBoolean(text) {
+			return fmt.Errorf("cannot parse as bool: invalid syntax")
+		}
+		switch string(text) {
+		case "true":
+			v.AsBool = true
+			return nil
+		case "false":
+			v.AsBool = false
+			return nil
+		}
+		x, err := strconv.ParseBool(string(text))
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse as bool: %s", err)
+			return fmt.Errorf("cannot parse as bool: %s", err)
 		}
 		v.AsBool = x
 	case StringValue:
-		v.AsString = text
+		v.AsString = string(text)
 	case TimeValue:
-		x, err := time.Parse("2006-01-02T15:04:05.999", text)
+		clock, offset := splitTimeOffset(text)
+		if p.opts.Strict && !isTime(text) {
+			if len(offset) > 0 && isTime(clock) {
+				return fmt.Errorf("cannot parse as time: offset %q is not allowed", offset)
+			}
+			return fmt.Errorf("cannot parse as time: invalid syntax")
+		}
+		if x, ok := parseSimpleTime(text); ok {
+			v.AsTime = x
+			return nil
+		}
+		layout := "2006-01-02T15:04:05.999"
+		if len(offset) > 0 {
+			layout += "Z07:00"
+		}
+		x, err := time.Parse(layout, string(text))
+		if err != nil {
+			return fmt.Errorf("cannot parse as time: %s", err)
+		}
+		v.AsTime = x
+	case DecimalValue:
+		if p.opts.Strict && !isDecimal(text) {
+			return fmt.Errorf("cannot parse as decimal: invalid syntax")
+		}
+		x, err := ParseDecimal(string(text))
+		if err != nil {
+			return fmt.Errorf("cannot parse as decimal: %s", err)
+		}
+		v.AsCustom = x
+	case DateValue:
+		if p.opts.Strict && !isDate(text) {
+			return fmt.Errorf("cannot parse as date: invalid syntax")
+		}
+		if x, ok := parseSimpleDate(text); ok {
+			v.AsTime = x
+			return nil
+		}
+		x, err := time.Parse("2006-01-02", string(text))
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse as time: %s", err)
+			return fmt.Errorf("cannot parse as date: %s", err)
 		}
 		v.AsTime = x
+	case DurationValue:
+		if p.opts.Strict && !isDuration(text) {
+			return fmt.Errorf("cannot parse as duration: invalid syntax")
+		}
+		x, err := ParseDuration(string(text))
+		if err != nil {
+			return fmt.Errorf("cannot parse as duration: %s", err)
+		}
+		v.AsInt = int64(x)
+	case BytesValue:
+		if p.opts.Strict && !isBytes(text) {
+			return fmt.Errorf("cannot parse as bytes: invalid syntax")
+		}
+		var x []byte
+		var err error
+		if len(text) >= 2 && text[0] == '0' && text[1] == 'x' {
+			x = make([]byte, hex.DecodedLen(len(text)-2))
+			_, err = hex.Decode(x, text[2:])
+		} else {
+			x, err = base64.StdEncoding.DecodeString(string(text))
+		}
+		if err != nil {
+			return fmt.Errorf("cannot parse as bytes: %s", err)
+		}
+		v.AsCustom = x
+	case UUIDValue:
+		if p.opts.Strict && !isUUID(text) {
+			return fmt.Errorf("cannot parse as uuid: invalid syntax")
+		}
+		x, err := ParseUUID(string(text))
+		if err != nil {
+			return fmt.Errorf("cannot parse as uuid: %s", err)
+		}
+		v.AsCustom = x
+	case EnumValue:
+		x, ok := column.symbol(text)
+		if !ok {
+			return fmt.Errorf("cannot parse as enum: %q is not one of %s", text, strings.Join(column.Symbols, ","))
+		}
+		v.AsString = x
+	case ListValue:
+		return p.parseList(v, column, text)
 	default:
-		panic("wrong column type")
+		return parseCustom(v, colType, text)
+	}
+	return nil
+}
+
+// parseSimpleInt parses an integer of at most 18 digits with an
+// optional sign, which always fits into an int64. It returns false
+// for all other texts.
+func parseSimpleInt(text []byte) (int64, bool) {
+	neg := false
+	if len(text) > 0 && (text[0] == '-' || text[0] == '+') {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	if len(text) == 0 || len(text) > 18 {
+		return 0, false
+	}
+	var x int64
+	for _, c := range text {
+		if !isDigit(c) {
+			return 0, false
+		}
+		x = x*10 + int64(c-'0')
+	}
+	if neg {
+		x = -x
+	}
+	return x, true
+}
+
+// float64pow10 holds the powers of ten that a float64 represents exactly.
+var float64pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+	1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// parseSimpleFloat parses a float without exponent and with at most
+// 15 digits, like "-12.50". The digits and the power of ten are exact
+// in a float64, so a single division yields the correctly rounded
+// result, as strconv.ParseFloat would. It returns false for all other
+// texts.
+func parseSimpleFloat(text []byte) (float64, bool) {
+	neg := false
+	if len(text) > 0 && (text[0] == '-' || text[0] == '+') {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	var mantissa int64
+	digits, frac := 0, -1
+	for i, c := range text {
+		if c == '.' && frac < 0 && i > 0 {
+			frac = 0
+			continue
+		}
+		if !isDigit(c) {
+			return 0, false
+		}
+		mantissa = mantissa*10 + int64(c-'0')
+		digits++
+		if frac >= 0 {
+			frac++
+		}
+	}
+	if digits == 0 || digits > 15 || frac == 0 {
+		return 0, false
+	}
+	x := float64(mantissa)
+	if frac > 0 {
+		x /= float64pow10[frac]
+	}
+	if neg {
+		x = -x
+	}
+	return x, true
+}
+
+// parseSimpleTime parses a time in the format of rfc.txt, section 3.5,
+// with at most 9 fraction digits. It returns false for all other texts,
+// including texts with an invalid month, day, hour, minute or second.
+func parseSimpleTime(text []byte) (time.Time, bool) {
+	if !isTime(text) {
+		return time.Time{}, false
+	}
+	num := func(i, n int) int {
+		x := 0
+		for _, c := range text[i : i+n] {
+			x = x*10 + int(c-'0')
+		}
+		return x
+	}
+	year, month, day := num(0, 4), num(5, 2), num(8, 2)
+	hour, min, sec := num(11, 2), num(14, 2), num(17, 2)
+	if month < 1 || month > 12 || day < 1 || day > daysIn(time.Month(month), year) {
+		return time.Time{}, false
+	}
+	if hour > 23 || min > 59 || sec > 59 {
+		return time.Time{}, false
+	}
+	nsec := 0
+	if len(text) > 19 {
+		frac := text[20:]
+		if len(frac) > 9 {
+			return time.Time{}, false
+		}
+		nsec = num(20, len(frac))
+		for i := len(frac); i < 9; i++ {
+			nsec *= 10
+		}
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, nsec, time.UTC), true
+}
+
+// splitTimeOffset splits a time text into the clock part and a
+// trailing UTC offset like "Z" or "+02:00", as defined in RFC 3339.
+// If there is no offset, the offset part is empty.
+func splitTimeOffset(text []byte) (clock, offset []byte) {
+	n := len(text)
+	switch {
+	case n > 19 && text[n-1] == 'Z':
+		return text[:n-1], text[n-1:]
+	case n > 24 && (text[n-6] == '+' || text[n-6] == '-') && text[n-3] == ':':
+		return text[:n-6], text[n-6:]
+	}
+	return text, nil
+}
+
+// parseSimpleDate parses a date in the format of rfc.txt, section 3.7.
+// It returns false for all other texts, including texts with an invalid
+// month or day.
+func parseSimpleDate(text []byte) (time.Time, bool) {
+	if !isDate(text) {
+		return time.Time{}, false
+	}
+	year := int(text[0]-'0')*1000 + int(text[1]-'0')*100 + int(text[2]-'0')*10 + int(text[3]-'0')
+	month := int(text[5]-'0')*10 + int(text[6]-'0')
+	day := int(text[8]-'0')*10 + int(text[9]-'0')
+	if month < 1 || month > 12 || day < 1 || day > daysIn(time.Month(month), year) {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// daysIn returns the number of days of a month.
+func daysIn(month time.Month, year int) int {
+	if month == time.February {
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	}
+	if month == time.April || month == time.June || month == time.September || month == time.November {
+		return 30
+	}
+	return 31
+}
+
+var errFraction = errors.New("exponent yields fraction")
+
+// parseInt parses an integer. Other than strconv.ParseInt, parseInt
+// accepts an exponent part as defined in rfc.txt, section 3.1, for
+// example "1e3" or "25E+2". It fails if the exponent yields a
+// fraction, or if the value does not fit into an int64.
+func parseInt(text string) (int64, error) {
+	i := strings.IndexAny(text, "eE")
+	if i < 0 {
+		return strconv.ParseInt(text, 10, 64)
+	}
+	numError := func(err error) error {
+		return &strconv.NumError{Func: "ParseInt", Num: text, Err: err}
+	}
+	// parse mantissa
+	sign, digits := "", text[:i]
+	if digits != "" && (digits[0] == '-' || digits[0] == '+') {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, numError(strconv.ErrSyntax)
+	}
+	// parse exponent
+	exp, err := strconv.ParseInt(text[i+1:], 10, 32)
+	if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+		return 0, numError(strconv.ErrSyntax)
+	}
+	// zero stays zero, whatever the exponent is
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return 0, nil
+	}
+	// shift digits by exponent
+	if exp < 0 {
+		n := -exp
+		if n > int64(len(digits)) || strings.Trim(digits[len(digits)-int(n):], "0") != "" {
+			return 0, numError(errFraction)
+		}
+		digits = digits[:len(digits)-int(n)]
+	} else {
+		if exp > 19 {
+			return 0, numError(strconv.ErrRange)
+		}
+		digits += strings.Repeat("0", int(exp))
+	}
+	x, err := strconv.ParseInt(sign+digits, 10, 64)
+	if err != nil {
+		return 0, numError(err.(*strconv.NumError).Err)
 	}
-	return v, nil
+	return x, nil
 }
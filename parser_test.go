@@ -2,13 +2,18 @@ package tdat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/cvilsmeier/tdat/assert"
 	"io/ioutil"
+	"math"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestParse(t *testing.T) {
@@ -17,7 +22,7 @@ func TestParse(t *testing.T) {
 	for _, fi := range fis {
 		fname := fi.Name()
 		if !strings.HasPrefix(fname, "parser_") {
-			break
+			continue
 		}
 		t.Run(fname, func(t *testing.T) {
 			buf, err := ioutil.ReadFile("testdata/" + fname)
@@ -27,24 +32,553 @@ func TestParse(t *testing.T) {
 			assert.Truef(t, i >= 0, "i was %d", i)
 			input := all[:i]
 			exp := strings.TrimSpace(all[i+9:])
-			// parse input
-			model, err := ParseFromString(input)
-			// stringify parse result
-			act := ""
-			if model != nil {
-				for _, table := range model.Tables {
-					act += stringifyTable(table)
+			// parse input from a string, a slow reader and bytes
+			models := make([]*Model, 3)
+			errs := make([]error, 3)
+			models[0], errs[0] = ParseFromString(input)
+			models[1], errs[1] = ParseFromReader(iotest.OneByteReader(strings.NewReader(input)))
+			models[2], errs[2] = ParseBytes([]byte(input))
+			for k, model := range models {
+				// stringify parse result
+				act := ""
+				if model != nil {
+					for _, table := range model.Tables {
+						act += stringifyTable(table)
+					}
+				}
+				if errs[k] != nil {
+					act += errs[k].Error()
 				}
+				act = strings.TrimSpace(act)
+				assert.EqStr(t, exp, act)
 			}
-			if err != nil {
-				act += err.Error()
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	fis, err := ioutil.ReadDir("testdata")
+	assert.Truef(t, err == nil, "err was %s", err)
+	for _, fi := range fis {
+		fname := fi.Name()
+		if !strings.HasPrefix(fname, "conformance_") {
+			continue
+		}
+		t.Run(fname, func(t *testing.T) {
+			buf, err := ioutil.ReadFile("testdata/" + fname)
+			assert.Truef(t, err == nil, "err was %s", err)
+			for lineIndex, line := range strings.Split(string(buf), "\n") {
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				// <mode> <type> <cell> => <expected>
+				i := strings.LastIndex(line, " => ")
+				assert.Truef(t, i >= 0, "%s:%d: no =>", fname, lineIndex+1)
+				fields := strings.SplitN(line[:i], " ", 3)
+				assert.Truef(t, len(fields) == 3, "%s:%d: invalid line", fname, lineIndex+1)
+				mode, colType, cell, exp := fields[0], fields[1], fields[2], line[i+4:]
+				input := fmt.Sprintf("t\n|c:%s\n|%s\n", colType, cell)
+				for _, strict := range []bool{true, false} {
+					if (strict && mode == "lenient") || (!strict && mode == "strict") {
+						continue
+					}
+					model, err := ParseOptions{Strict: strict}.ParseFromString(input)
+					act := ""
+					if err != nil {
+						act = "error: " + err.(*ParseError).Cause.Error()
+					} else if value := model.Tables[0].Rows[0].Values[0]; value.Type == StringValue {
+						act = strconv.Quote(value.AsString)
+					} else {
+						act = stringifyValue(value)
+					}
+					assert.EqStrf(t, exp, act, "%s:%d, strict %t", fname, lineIndex+1, strict)
+				}
 			}
-			act = strings.TrimSpace(act)
-			assert.EqStr(t, exp, act)
 		})
 	}
 }
 
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		exp   string
+	}{
+		{"separator", "|", "1:1 \"\" \"\" -1 unexpected separator"},
+		{"column", "persons\n|id:i|name:x\n", "2:7 \"persons\" \"\" -1 invalid column type"},
+		{"int", "persons\n|id:i|name:s\n|1|\"joe\"\n|x|\"jane\"\n", "4:2 \"persons\" \"id\" 1 cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax"},
+		{"string", "persons\n|id:i|name:s\n|1|\"joe", "3:8 \"persons\" \"name\" 0 unterminated string"},
+		{"too_many", "persons\n|id:i\n|1|2\n", "3:3 \"persons\" \"\" 0 too many data values"},
+		{"enum", "persons\n|status:e(active,blocked)\n|active\n|Active\n", "4:2 \"persons\" \"status\" 1 cannot parse as enum: \"Active\" is not one of active,blocked"},
+		{"enum_column", "persons\n|status:e(active,,blocked)\n", "2:2 \"persons\" \"\" -1 invalid column definition: symbol is empty"},
+		{"enum_no_symbols", "persons\n|status:e\n", "2:2 \"persons\" \"\" -1 invalid column definition: enum has no symbols"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ParseFromString(testCase.input)
+			var parseError *ParseError
+			assert.Truef(t, errors.As(err, &parseError), "err was %v", err)
+			e := parseError
+			act := fmt.Sprintf("%d:%d %q %q %d %s", e.Line, e.Pos, e.TableName, e.ColumnName, e.RowIndex, e.Cause)
+			assert.EqStr(t, testCase.exp, act)
+		})
+	}
+}
+
+func TestParseCollectErrors(t *testing.T) {
+	input := "|1\n"
+	input += "persons\n"
+	input += "|id:i   |name:s\n"
+	input += "|1      |\"joe\"\n"
+	input += "|x      |\"jane\"\n"
+	input += "|3      |\"jim\" |\n"
+	input += "|4      |\"jo\\e\"\n"
+	input += "|5      |\"jeff\"\n"
+	input += "cars\n"
+	input += "|id:i   |brand:x\n"
+	input += "|1      |\"bmw\"\n"
+	input += "cities\n"
+	input += "|id:i   |name:s\n"
+	input += "|1      |\"berlin\"\n"
+	input += "|2"
+	opts := ParseOptions{CollectErrors: true}
+	model, err := opts.ParseFromString(input)
+	assert.True(t, model != nil)
+	act := ""
+	for _, table := range model.Tables {
+		act += fmt.Sprintf("%s(%d rows) ", table.Name, len(table.Rows))
+	}
+	assert.EqStr(t, "persons(2 rows) cars(0 rows) cities(1 rows) ", act)
+	errorList, ok := err.(ErrorList)
+	assert.Truef(t, ok, "err was %v", err)
+	act = ""
+	for _, e := range errorList {
+		act += e.Error() + "\n"
+	}
+	exp := "line 1, pos 1: unexpected separator\n"
+	exp += "line 5, pos 2: cannot parse as int: strconv.ParseInt: parsing \"x\": invalid syntax\n"
+	exp += "line 6, pos 16: too many data values\n"
+	exp += "line 7, pos 14: illegal escape sequence\n"
+	exp += "line 10, pos 10: invalid column type\n"
+	exp += "line 15, pos 3: too few data values\n"
+	assert.EqStr(t, exp, act)
+	assert.EqStr(t, "line 1, pos 1: unexpected separator (and 5 more errors)", err.Error())
+	// stop after MaxErrors
+	opts = ParseOptions{CollectErrors: true, MaxErrors: 2}
+	model, err = opts.ParseFromString(input)
+	assert.True(t, model != nil)
+	assert.EqInt(t, 1, len(model.Tables))
+	assert.EqInt(t, 1, len(model.Tables[0].Rows))
+	assert.EqInt(t, 2, len(err.(ErrorList)))
+	// no errors
+	model, err = opts.ParseFromString("persons\n|id:i\n|1\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 1, len(model.Tables[0].Rows))
+}
+
+func TestParseStrict(t *testing.T) {
+	testCases := []struct {
+		colType ValueType
+		cell    string
+		strict  string
+		lenient string
+	}{
+		{IntValue, "0", "0", "0"},
+		{IntValue, "-12", "-12", "-12"},
+		{IntValue, "007", "cannot parse as int: invalid syntax", "7"},
+		{IntValue, "+7", "cannot parse as int: invalid syntax", "7"},
+		{IntValue, "-", "cannot parse as int: invalid syntax", "cannot parse as int: strconv.ParseInt: parsing \"-\": invalid syntax"},
+		{IntValue, "\"7\"", "value must not be quoted", "7"},
+		{FloatValue, "1.5", "1.500000", "1.500000"},
+		{FloatValue, "-0.5e-2", "-0.005000", "-0.005000"},
+		{FloatValue, "1.", "cannot parse as float: invalid syntax", "1.000000"},
+		{FloatValue, ".5", "cannot parse as float: invalid syntax", "0.500000"},
+		{FloatValue, "Inf", "cannot parse as float: invalid syntax", "+Inf"},
+		{FloatValue, "NaN", "cannot parse as float: invalid syntax", "NaN"},
+		{FloatValue, "0x1p-2", "cannot parse as float: invalid syntax", "0.250000"},
+		{FloatValue, "1e", "cannot parse as float: invalid syntax", "cannot parse as float: strconv.ParseFloat: parsing \"1e\": invalid syntax"},
+		{BoolValue, "true", "true", "true"},
+		{BoolValue, "false", "false", "false"},
+		{BoolValue, "1", "cannot parse as bool: invalid syntax", "true"},
+		{BoolValue, "T", "cannot parse as bool: invalid syntax", "true"},
+		{BoolValue, "TRUE", "cannot parse as bool: invalid syntax", "true"},
+		{StringValue, "\"joe\"", "joe", "joe"},
+		{StringValue, "joe", "string value must be quoted", "joe"},
+		{TimeValue, "2017-12-12T10:00:00.333", "2017-12-12 10:00:00.333 +0000 UTC", "2017-12-12 10:00:00.333 +0000 UTC"},
+		{TimeValue, "2017-12-12T10:00:00", "2017-12-12 10:00:00 +0000 UTC", "2017-12-12 10:00:00 +0000 UTC"},
+		{TimeValue, "2017-12-12T10:00:00.", "cannot parse as time: invalid syntax", "cannot parse as time: parsing time \"2017-12-12T10:00:00.\": extra text: \".\""},
+		{TimeValue, "\"2017-12-12T10:00:00\"", "value must not be quoted", "2017-12-12 10:00:00 +0000 UTC"},
+		{TimeValue, "2017-12-12T10:00:00.123456789", "2017-12-12 10:00:00.123456789 +0000 UTC", "2017-12-12 10:00:00.123456789 +0000 UTC"},
+		{TimeValue, "2017-12-12T10:00:00Z", "cannot parse as time: offset \"Z\" is not allowed", "2017-12-12 10:00:00 +0000 UTC"},
+		{TimeValue, "2017-12-12T10:00:00.5+02:00", "cannot parse as time: offset \"+02:00\" is not allowed", "2017-12-12 10:00:00.5 +0200 +0200"},
+		{TimeValue, "2017-12-12T10:00:00.000000001-05:30", "cannot parse as time: offset \"-05:30\" is not allowed", "2017-12-12 10:00:00.000000001 -0530 -0530"},
+		{TimeValue, "2017-12-12T10:00:00+2", "cannot parse as time: invalid syntax", "cannot parse as time: parsing time \"2017-12-12T10:00:00+2\": extra text: \"+2\""},
+		{TimeValue, "2017-13-12T10:00:00", "cannot parse as time: parsing time \"2017-13-12T10:00:00\": month out of range", "cannot parse as time: parsing time \"2017-13-12T10:00:00\": month out of range"},
+		{DateValue, "1972-05-03", "1972-05-03 00:00:00 +0000 UTC", "1972-05-03 00:00:00 +0000 UTC"},
+		{DateValue, "2016-02-29", "2016-02-29 00:00:00 +0000 UTC", "2016-02-29 00:00:00 +0000 UTC"},
+		{DateValue, "2017-02-29", "cannot parse as date: parsing time \"2017-02-29\": day out of range", "cannot parse as date: parsing time \"2017-02-29\": day out of range"},
+		{DateValue, "1972-5-3", "cannot parse as date: invalid syntax", "cannot parse as date: parsing time \"1972-5-3\" as \"2006-01-02\": cannot parse \"5-3\" as \"01\""},
+		{DateValue, "1972-05-03T00:00:00", "cannot parse as date: invalid syntax", "cannot parse as date: parsing time \"1972-05-03T00:00:00\": extra text: \"T00:00:00\""},
+		{DurationValue, "PT1H30M", "1h30m0s", "1h30m0s"},
+		{DurationValue, "P1DT0.5S", "24h0m0.5s", "24h0m0.5s"},
+		{DurationValue, "-PT90S", "-1m30s", "-1m30s"},
+		{DurationValue, "P2D", "48h0m0s", "48h0m0s"},
+		{DurationValue, "pt1h", "cannot parse as duration: invalid syntax", "1h0m0s"},
+		{DurationValue, "+PT1S", "cannot parse as duration: invalid syntax", "1s"},
+		{DurationValue, "P1M", "cannot parse as duration: invalid syntax", "cannot parse as duration: parsing \"P1M\": invalid syntax"},
+		{DurationValue, "1h30m", "cannot parse as duration: invalid syntax", "cannot parse as duration: parsing \"1h30m\": invalid syntax"},
+		{BytesValue, "0x", "[]", "[]"},
+		{BytesValue, "0x00ff10", "[0 255 16]", "[0 255 16]"},
+		{BytesValue, "0xABcd", "[171 205]", "[171 205]"},
+		{BytesValue, "0xabc", "cannot parse as bytes: invalid syntax", "cannot parse as bytes: encoding/hex: odd length hex string"},
+		{BytesValue, "0xzz", "cannot parse as bytes: invalid syntax", "cannot parse as bytes: encoding/hex: invalid byte: U+007A 'z'"},
+		{BytesValue, "AP8Q", "cannot parse as bytes: invalid syntax", "[0 255 16]"},
+		{BytesValue, "AP8", "cannot parse as bytes: invalid syntax", "cannot parse as bytes: illegal base64 data at input byte 0"},
+		{UUIDValue, "123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{UUIDValue, "123E4567-E89B-12D3-A456-426614174000", "123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{UUIDValue, "{123e4567-e89b-12d3-a456-426614174000}", "cannot parse as uuid: invalid syntax", "123e4567-e89b-12d3-a456-426614174000"},
+		{UUIDValue, "123e4567e89b12d3a456426614174000", "cannot parse as uuid: invalid syntax", "123e4567-e89b-12d3-a456-426614174000"},
+		{UUIDValue, "123e4567-e89b-12d3-a456-42661417400", "cannot parse as uuid: invalid syntax", "cannot parse as uuid: parsing \"123e4567-e89b-12d3-a456-42661417400\": invalid syntax"},
+	}
+	for _, testCase := range testCases {
+		input := fmt.Sprintf("t\n|c:%c\n|%s\n", testCase.colType, testCase.cell)
+		for _, strict := range []bool{true, false} {
+			exp := testCase.lenient
+			if strict {
+				exp = testCase.strict
+			}
+			model, err := ParseOptions{Strict: strict}.ParseFromString(input)
+			act := ""
+			if err != nil {
+				act = err.(*ParseError).Cause.Error()
+			} else {
+				act = stringifyValue(model.Tables[0].Rows[0].Values[0])
+			}
+			assert.EqStrf(t, exp, act, "cell %s strict %t", testCase.cell, strict)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	input := "persons\n"
+	input += "|id:i|name:s|age:i\n"
+	input += "|1|\"joe\"|12\n"
+	input += "|2|\"jim\"|13\n"
+	input += "|3|\"jefferson\"|14\n"
+	input += "cars\n"
+	testCases := []struct {
+		opts ParseOptions
+		exp  string
+	}{
+		{ParseOptions{}, ""},
+		{ParseOptions{MaxBytes: 200, MaxTables: 2, MaxColumns: 3, MaxRowsPerTable: 3, MaxStringLen: 11}, ""},
+		{ParseOptions{MaxBytes: 20}, "line 2, pos 13: limit exceeded: MaxBytes is 20"},
+		{ParseOptions{MaxTables: 1}, "line 6, pos 1: limit exceeded: MaxTables is 1"},
+		{ParseOptions{MaxColumns: 2}, "line 2, pos 14: limit exceeded: MaxColumns is 2"},
+		{ParseOptions{MaxRowsPerTable: 2}, "line 5, pos 1: limit exceeded: MaxRowsPerTable is 2"},
+		{ParseOptions{MaxStringLen: 6}, "line 1, pos 7: limit exceeded: MaxStringLen is 6"},
+		{ParseOptions{MaxStringLen: 8}, "line 5, pos 14: limit exceeded: MaxStringLen is 8"},
+		{ParseOptions{MaxStringLen: 6, CollectErrors: true}, "line 1, pos 7: limit exceeded: MaxStringLen is 6"},
+	}
+	for _, testCase := range testCases {
+		model, err := testCase.opts.ParseFromString(input)
+		if testCase.exp == "" {
+			assert.Truef(t, err == nil, "err was %s", err)
+			assert.EqInt(t, 2, len(model.Tables))
+			continue
+		}
+		assert.True(t, model == nil)
+		assert.True(t, err != nil)
+		assert.EqStr(t, testCase.exp, err.Error())
+		var limitError *LimitExceededError
+		assert.True(t, errors.As(err, &limitError))
+	}
+}
+
+func TestParseLimitsPadded(t *testing.T) {
+	// the padding of columns does not count toward MaxStringLen
+	builder := NewBuilder()
+	table := builder.AddTable("t")
+	table.AddStringColumn("a")
+	table.AddIntColumn("b")
+	table.AddListColumn("c", IntValue)
+	table.AddIntColumn("d")
+	row := table.AddRow()
+	row.AddStringValue("abc")
+	row.AddIntValue(int64(12345))
+	row.AddListValue([]*Value{{Type: IntValue, AsInt: 1}})
+	row.AddIntValue(int64(1))
+	model, err := builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err := RenderToString(model, 20)
+	assert.Truef(t, err == nil, "err was %s", err)
+	input := txt + "# c  " + strings.Repeat(" ", 20) + "\n"
+	_, err = ParseOptions{MaxStringLen: 5}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	_, err = ParseOptions{MaxStringLen: 4}.ParseFromString(input)
+	assert.EqStr(t, "line 2, pos 48: limit exceeded: MaxStringLen is 4", err.Error())
+	_, err = ParseOptions{MaxStringLen: 5}.ParseFromString("t\n|a:s\n|a b  c   \n")
+	assert.EqStr(t, "line 3, pos 7: limit exceeded: MaxStringLen is 5", err.Error())
+}
+
+func TestParseComments(t *testing.T) {
+	input := "# reference data\n"
+	input += "#\n"
+	input += "persons\n"
+	input += "  # the header\n"
+	input += "|id:i|name:s\n"
+	input += "|1|\"joe\"\n"
+	input += "#the second row \r\n"
+	input += "|2|\"#2\"\n"
+	input += "\n"
+	input += "# a table\n"
+	input += "# without header\n"
+	input += "empty # table\n"
+	input += "\n"
+	input += "# the end\n"
+	model, err := ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 2, len(model.Tables))
+	persons := model.Tables[0]
+	assert.EqStr(t, "[reference data ]", fmt.Sprintf("%v", persons.Comments))
+	assert.EqStr(t, "[the header]", fmt.Sprintf("%v", persons.Columns[0].Comments))
+	assert.EqInt(t, 0, len(persons.Columns[1].Comments))
+	assert.EqInt(t, 2, len(persons.Rows))
+	assert.EqInt(t, 0, len(persons.Rows[0].Comments))
+	assert.EqStr(t, "[the second row]", fmt.Sprintf("%v", persons.Rows[1].Comments))
+	assert.EqStr(t, "#2", persons.Rows[1].Values[1].AsString)
+	empty := model.Tables[1]
+	assert.EqStr(t, "empty # table", empty.Name)
+	assert.EqStr(t, "a table|without header", strings.Join(empty.Comments, "|"))
+	assert.EqStr(t, "[the end]", fmt.Sprintf("%v", model.Comments))
+}
+
+func TestParsePositions(t *testing.T) {
+	input := "# persons\n"
+	input += "persons\n"
+	input += "|id:i  | name:s\n"
+	input += "|1     |\"j\\u00f6e\"  \n"
+	input += "  |2||\n"
+	input += "\n"
+	input += "empty\n"
+	model, err := ParseOptions{Positions: true, CollectErrors: true}.ParseFromString(input)
+	assert.True(t, err != nil)
+	assert.EqStr(t, "line 5, pos 7: too many data values", err.Error())
+	persons := model.Tables[0]
+	assert.EqStr(t, "2:1-4:19", persons.Span().String())
+	assert.EqStr(t, "3:2-3:6", persons.ColumnSpan(0).String())
+	assert.EqStr(t, "3:10-3:16", persons.ColumnSpan(1).String())
+	assert.EqInt(t, 1, len(persons.Rows))
+	assert.EqStr(t, "4:1-4:19", persons.RowSpan(0).String())
+	assert.EqStr(t, "4:2-4:3", persons.ValueSpan(0, 0).String())
+	assert.EqStr(t, "4:9-4:19", persons.ValueSpan(0, 1).String())
+	assert.EqStr(t, "-", persons.RowSpan(1).String())
+	assert.EqStr(t, "-", persons.ValueSpan(0, 2).String())
+	assert.EqStr(t, "7:1-7:6", model.Tables[1].Span().String())
+	// null values
+	model, err = ParseOptions{Positions: true}.ParseFromString("t\n|a:i|b:s\n|  | \"x\"\n| 1 |\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	table := model.Tables[0]
+	assert.EqStr(t, "3:2-3:2", table.ValueSpan(0, 0).String())
+	assert.EqStr(t, "3:6-3:9", table.ValueSpan(0, 1).String())
+	assert.EqStr(t, "3:1-3:9", table.RowSpan(0).String())
+	assert.EqStr(t, "4:6-4:6", table.ValueSpan(1, 1).String())
+	assert.EqStr(t, "4:1-4:6", table.RowSpan(1).String())
+	assert.EqStr(t, "1:1-4:6", table.Span().String())
+	// spans are projected to the selected columns, and are kept in columnar form
+	opts := ParseOptions{Positions: true, Columnar: true, Columns: map[string][]string{"t": {"b"}}}
+	model, err = opts.ParseFromString("t\n|a:i|b:s\n|  | \"x\"\n| 1 |\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	table = model.Tables[0]
+	assert.EqStr(t, "2:6-2:9", table.ColumnSpan(0).String())
+	assert.EqStr(t, "3:6-3:9", table.ValueSpan(0, 0).String())
+	assert.EqStr(t, "4:6-4:6", table.ValueSpan(1, 0).String())
+	assert.EqStr(t, "-", table.ValueSpan(1, 1).String())
+	table.ToRows()
+	assert.EqStr(t, "4:1-4:6", table.RowSpan(1).String())
+	// positions are not recorded by default
+	model, err = ParseFromString("persons\n|id:i\n|1\n")
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "-", model.Tables[0].Span().String())
+	assert.True(t, !model.Tables[0].ColumnSpan(0).Start.IsValid())
+}
+
+func TestParseProjection(t *testing.T) {
+	input := "# persons\n"
+	input += "persons\n"
+	input += "|id:i|name:s|age:i\n"
+	input += "|1|\"joe\"|x\n"
+	input += "|2|\"sue\\\"\n|\ncars\"|\n"
+	input += "# cars\n"
+	input += "cars\n"
+	input += "|id:i|built:t\n"
+	input += "|1|2001-02-03T04:05:06\n"
+	input += "\n"
+	input += "trucks\n"
+	input += "|id:i\n"
+	input += "|1\n"
+	// select tables
+	model, err := ParseOptions{Tables: []string{"cars", "bikes"}}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 1, len(model.Tables))
+	cars := model.Tables[0]
+	assert.EqStr(t, "cars", cars.Name)
+	assert.EqStr(t, "[cars]", fmt.Sprintf("%v", cars.Comments))
+	assert.EqInt(t, 2, len(cars.Columns))
+	assert.EqInt(t, 1, len(cars.Rows))
+	// select columns
+	opts := ParseOptions{Columns: map[string][]string{"persons": {"name", "id"}, "cars": {}}}
+	model, err = opts.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 3, len(model.Tables))
+	persons := model.Tables[0]
+	assert.EqStr(t, "[id name]", fmt.Sprintf("%v", []string{persons.Columns[0].Name, persons.Columns[1].Name}))
+	assert.EqInt(t, 2, len(persons.Columns))
+	assert.EqInt(t, 2, len(persons.Rows))
+	assert.EqInt(t, 2, len(persons.Rows[1].Values))
+	assert.EqStr(t, "sue\"\n|\ncars", persons.Rows[1].Values[1].AsString)
+	assert.EqInt(t, 0, len(model.Tables[1].Columns))
+	assert.EqInt(t, 0, len(model.Tables[1].Rows[0].Values))
+	assert.EqInt(t, 1, len(model.Tables[2].Columns))
+	// row widths are checked, errors after skipped tables have correct positions
+	input = "persons\n|id:i|name:s\n|1|\"joe\"\n|2\n"
+	opts = ParseOptions{Columns: map[string][]string{"persons": {"id"}}}
+	_, err = opts.ParseFromString(input)
+	assert.EqStr(t, "line 4, pos 3: too few data values", err.Error())
+	_, err = ParseOptions{Tables: []string{"cars"}}.ParseFromString(input + "cars\n|id:x\n")
+	assert.EqStr(t, "line 6, pos 2: invalid column type", err.Error())
+}
+
+func TestParseWithContext(t *testing.T) {
+	input := string(benchmarkInput(3000))
+	// progress
+	progress := []string{}
+	opts := ParseOptions{Progress: func(bytes int64, rows int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", bytes, rows))
+	}}
+	model, err := opts.ParseWithContext(context.Background(), strings.NewReader(input))
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqInt(t, 3000, len(model.Tables[0].Rows))
+	assert.EqStr(t, "[48170/1024 96298/2048 141042/3000]", fmt.Sprintf("%v", progress))
+	// cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.Progress = func(bytes int64, rows int) {
+		cancel()
+	}
+	model, err = opts.ParseWithContext(ctx, strings.NewReader(input))
+	assert.True(t, model == nil)
+	assert.True(t, err == context.Canceled)
+	// tables without rows, skipped tables and parallel parsing
+	// stop as well
+	headers := strings.Repeat("t\n|x:i\n", 10)
+	skipped := "skipped\n|x:i\n" + strings.Repeat("|1\n", 2000)
+	testCases := []struct {
+		input string
+		opts  ParseOptions
+	}{
+		{headers, ParseOptions{}},
+		{skipped, ParseOptions{Tables: []string{"t"}}},
+		{input, ParseOptions{Workers: 2}},
+		{input, ParseOptions{Workers: 2, CollectErrors: true}},
+	}
+	for _, testCase := range testCases {
+		model, err = testCase.opts.ParseWithContext(ctx, strings.NewReader(testCase.input))
+		assert.True(t, model == nil)
+		assert.Truef(t, err == context.Canceled, "err was %v", err)
+	}
+	// skipped rows report progress
+	progress = progress[:0]
+	opts = ParseOptions{Tables: []string{"t"}, Progress: func(bytes int64, rows int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", bytes, rows))
+	}}
+	_, err = opts.ParseWithContext(context.Background(), strings.NewReader(skipped))
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "[7/0 3084/0 6013/0]", fmt.Sprintf("%v", progress))
+}
+
+func TestParseEnum(t *testing.T) {
+	input := "persons\n|id:i|status:e(active,blocked,deleted)|x:e(a:e(b)\n|1|active|b\n|2||b\n|3|deleted|\n"
+	model, err := ParseOptions{Strict: true, Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	persons := model.Tables[0]
+	assert.EqStr(t, "status", persons.Columns[1].Name)
+	assert.EqStr(t, "[active blocked deleted]", fmt.Sprintf("%v", persons.Columns[1].Symbols))
+	assert.EqStr(t, "x:e(a", persons.Columns[2].Name)
+	assert.EqStr(t, "[b]", fmt.Sprintf("%v", persons.Columns[2].Symbols))
+	assert.EqStr(t, "active", persons.StringAt(0, 1))
+	assert.True(t, persons.IsNull(1, 1))
+	assert.EqStr(t, "deleted", persons.StringAt(2, 1))
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input+"\n", txt)
+	// strict mode rejects quoted symbols
+	input = "persons\n|status:e(active,blocked)\n|\"active\"\n"
+	_, err = ParseOptions{Strict: true}.ParseFromString(input)
+	assert.EqStr(t, "line 3, pos 2: value must not be quoted", err.Error())
+	model, err = ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "active", model.Tables[0].Rows[0].Values[0].AsString)
+	// builder
+	builder := NewBuilder()
+	table := builder.AddTable("persons")
+	table.AddEnumColumn("status", "active", "blocked")
+	table.AddRow().AddEnumValue("blocked")
+	table.AddRow().AddEnumValue(nil)
+	model, err = builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "persons\n|status:e(active,blocked)\n|blocked\n|\n\n", txt)
+	table.AddRow().AddEnumValue("deleted")
+	_, err = builder.Build()
+	assert.EqStr(t, "table \"persons\": row 3, value 1: \"deleted\" is not a symbol of column \"status\"", err.Error())
+}
+
+func TestParseSimpleValues(t *testing.T) {
+	ints := []string{"0", "-0", "+7", "007", "123456789012345678", "-999999999999999999", "1234567890123456789", "1e3", "", "-", "1.0", "x"}
+	for _, text := range ints {
+		x, ok := parseSimpleInt([]byte(text))
+		if ok {
+			exp, err := strconv.ParseInt(text, 10, 64)
+			assert.Truef(t, err == nil, "%q: err was %s", text, err)
+			assert.Truef(t, exp == x, "%q: expected %d but was %d", text, exp, x)
+		}
+	}
+	floats := []string{"0", "-0.0", "1.5", "13000.00", "0.1", "0.3", "-12.125", "123456789.012345", "99999999999999.9", "1234567890123456", "1.", ".5", "1e3", "1.2.3", "", "-"}
+	for _, text := range floats {
+		x, ok := parseSimpleFloat([]byte(text))
+		exp, err := strconv.ParseFloat(text, 64)
+		if ok {
+			assert.Truef(t, err == nil, "%q: err was %s", text, err)
+			assert.Truef(t, exp == x && math.Signbit(exp) == math.Signbit(x), "%q: expected %v but was %v", text, exp, x)
+		}
+	}
+	times := []string{
+		"2017-12-12T10:00:00",
+		"2017-12-12T10:00:00.113",
+		"2000-02-29T23:59:59.123456789",
+		"1900-02-29T00:00:00",
+		"2017-04-31T00:00:00",
+		"2017-13-01T00:00:00",
+		"2017-00-01T00:00:00",
+		"2017-01-01T24:00:00",
+		"2017-01-01T00:60:00",
+		"2017-01-01T00:00:60",
+		"2017-01-01T00:00:00.1234567891",
+		"2017-01-01T00:00:00.",
+		"2017-01-01 00:00:00",
+	}
+	for _, text := range times {
+		x, ok := parseSimpleTime([]byte(text))
+		exp, err := time.Parse("2006-01-02T15:04:05.999", text)
+		if ok {
+			assert.Truef(t, err == nil, "%q: err was %s", text, err)
+			assert.Truef(t, exp.Equal(x), "%q: expected %s but was %s", text, exp, x)
+		}
+		assert.Truef(t, ok == (err == nil && len(text) <= 29), "%q: ok was %t", text, ok)
+	}
+}
+
 func stringifyTable(table *Table) string {
 	str := fmt.Sprintf("table %q\n", table.Name)
 	for _, col := range table.Columns {
@@ -56,28 +590,41 @@ func stringifyTable(table *Table) string {
 			if val.Null {
 				str += fmt.Sprintf("  val null(%c)\n", val.Type)
 			} else {
-				valText := ""
-				switch val.Type {
-				case IntValue:
-					valText = fmt.Sprintf("%d", val.AsInt)
-				case FloatValue:
-					valText = fmt.Sprintf("%f", val.AsFloat)
-				case BoolValue:
-					valText = fmt.Sprintf("%t", val.AsBool)
-				case StringValue:
-					valText = fmt.Sprintf("%s", val.AsString)
-				case TimeValue:
-					valText = fmt.Sprintf("%s", val.AsTime)
-				default:
-					panic("wrong type")
-				}
-				str += fmt.Sprintf("  val %s(%c)\n", valText, val.Type)
+				str += fmt.Sprintf("  val %s(%c)\n", stringifyValue(val), val.Type)
 			}
 		}
 	}
 	return str
 }
 
+func stringifyValue(val *Value) string {
+	switch val.Type {
+	case IntValue:
+		return fmt.Sprintf("%d", val.AsInt)
+	case FloatValue:
+		return fmt.Sprintf("%f", val.AsFloat)
+	case BoolValue:
+		return fmt.Sprintf("%t", val.AsBool)
+	case StringValue:
+		return fmt.Sprintf("%s", val.AsString)
+	case TimeValue:
+		return fmt.Sprintf("%s", val.AsTime)
+	case DecimalValue:
+		return val.Decimal().String()
+	case DateValue:
+		return fmt.Sprintf("%s", val.AsTime)
+	case DurationValue:
+		return fmt.Sprintf("%s", val.Duration())
+	case BytesValue:
+		return fmt.Sprintf("%v", val.Bytes())
+	case UUIDValue:
+		return val.UUID().String()
+	case EnumValue:
+		return val.AsString
+	}
+	panic("wrong type")
+}
+
 func BenchmarkAllocateModel(b *testing.B) {
 	b.Skip()
 	rowCount := 200 * 1000
@@ -90,44 +637,68 @@ func BenchmarkAllocateModel(b *testing.B) {
 		flag, _ := strconv.ParseBool("true")
 		flagValue := &Value{Type: BoolValue, AsBool: flag}
 		nameValue := &Value{Type: StringValue, AsString: "joe"}
-		row := &Row{[]*Value{idValue, rateValue, flagValue, nameValue}}
+		row := &Row{Values: []*Value{idValue, rateValue, flagValue, nameValue}}
 		rows = append(rows, row)
 	}
 }
 
 func BenchmarkParseValue(b *testing.B) {
-	b.Skip()
 	p := &parser{}
-	rowCount := 100 * 1000
+	v := &Value{}
+	columns := []*Column{{Type: IntValue}, {Type: FloatValue}, {Type: BoolValue}, {Type: StringValue}, {Type: TimeValue}}
+	texts := [][]byte{[]byte("13"), []byte("1300.13"), []byte("true"), []byte("lorem ipsum"), []byte("2017-12-12T10:00:00.113")}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for j, column := range columns {
+			p.parseValue(v, column, texts[j])
+		}
+	}
+}
+
+func benchmarkInput(rowCount int) []byte {
+	b := &bytes.Buffer{}
+	b.WriteString("persons\n")
+	b.WriteString("|id:i|rate:f|flag:b|name:s|born:t\n")
 	for i := 0; i < rowCount; i++ {
-		p.parseValue(IntValue, "13")
-		p.parseValue(FloatValue, "1300.13")
-		p.parseValue(BoolValue, "true")
-		p.parseValue(StringValue, "lorem ipsum")
-		p.parseValue(TimeValue, "2017-12-12T10:00:00.113")
+		b.WriteString("|1|13000.00|true|\"joe\"|2017-12-12T10:00:00.113\n")
 	}
+	return b.Bytes()
 }
 
-func BenchmarkParseTdat(t *testing.B) {
-	rowCount := 100 * 1000
-	var input string
-	{
-		b := &bytes.Buffer{}
-		b.WriteString("persons\n")
-		b.WriteString("|id:i|rate:f|flag:b|name:s\n")
-		for i := 0; i < rowCount; i++ {
-			b.WriteString("|1|13000.00|true|\"joe\"\n")
+func BenchmarkParseTdat(b *testing.B) {
+	rowCount := 10 * 1000
+	input := string(benchmarkInput(rowCount))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, err := ParseFromString(input)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(m.Tables[0].Rows) != rowCount {
+			b.Fatal("wrong row count")
 		}
-		input = string(b.Bytes())
-	}
-	t.ResetTimer()
-	m, err := ParseFromString(input)
-	if err != nil {
-		panic(err)
 	}
-	if m == nil {
-		panic("no model")
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	rowCount := 10 * 1000
+	input := benchmarkInput(rowCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, err := ParseBytes(input)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(m.Tables[0].Rows) != rowCount {
+			b.Fatal("wrong row count")
+		}
 	}
+	allocs := testing.AllocsPerRun(1, func() {
+		ParseBytes(input)
+	})
+	b.ReportMetric(allocs/float64(rowCount), "allocs/row")
 }
 
 func BenchmarkParseJson(t *testing.B) {
@@ -0,0 +1,92 @@
+package tdat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A customType is an application-defined value type, see RegisterType.
+type customType struct {
+	parse  func(string) (interface{}, error)
+	format func(interface{}) string
+}
+
+var (
+	customTypesMu sync.RWMutex
+	customTypes   = map[ValueType]*customType{}
+)
+
+// RegisterType registers an application-defined value type, like an
+// ISBN or a geo point, under a type code. Afterwards, the type code
+// can be used in column definitions like any built-in type. The value
+// of a cell is held in Value.AsCustom.
+//
+// The parse function converts the text of a cell into a value, the
+// format function converts a value back into text. Like other values
+// but strings, custom values are not quoted, so format must return a
+// text that is a valid cell: not empty, without surrounding whitespace,
+// without separators and newlines, and not starting with a quotation
+// mark or a left bracket. In lists, the text must not contain commas
+// or right brackets either.
+//
+// The code must be an ASCII letter that is not used by a built-in type
+// or a type that was registered before. RegisterType panics if the code
+// is not available. It is usually called from an init function.
+func RegisterType(code ValueType, parse func(string) (interface{}, error), format func(interface{}) string) {
+	if !('a' <= code && code <= 'z' || 'A' <= code && code <= 'Z') {
+		panic(fmt.Sprintf("tdat: invalid type code '%c'", code))
+	}
+	if parse == nil || format == nil {
+		panic("tdat: parse and format must not be nil")
+	}
+	customTypesMu.Lock()
+	defer customTypesMu.Unlock()
+	if isBuiltinType(code) || customTypes[code] != nil {
+		panic(fmt.Sprintf("tdat: type code '%c' is already in use", code))
+	}
+	customTypes[code] = &customType{parse, format}
+}
+
+// lookupType returns the application-defined type for a type
+// code, or nil if there is none.
+func lookupType(code ValueType) *customType {
+	customTypesMu.RLock()
+	defer customTypesMu.RUnlock()
+	return customTypes[code]
+}
+
+// parseCustom parses text as a value of an application-defined type.
+func parseCustom(v *Value, code ValueType, text []byte) error {
+	ct := lookupType(code)
+	if ct == nil {
+		panic("wrong column type")
+	}
+	x, err := ct.parse(string(text))
+	if err != nil {
+		return fmt.Errorf("cannot parse as '%c': %s", code, err)
+	}
+	v.AsCustom = x
+	return nil
+}
+
+// formatCustom formats a value of an application-defined type.
+// It fails if the text is not a valid cell, or, if elem is true,
+// not a valid list element.
+func formatCustom(v *Value, elem bool) (string, error) {
+	ct := lookupType(v.Type)
+	if ct == nil {
+		return "", fmt.Errorf("unknown value type '%c'", v.Type)
+	}
+	text := ct.format(v.AsCustom)
+	invalid := "|\n"
+	if elem {
+		invalid = "|\n,]"
+	}
+	if text == "" || text != strings.TrimSpace(text) || strings.ContainsAny(text, invalid) ||
+		text[0] == '"' || text[0] == '[' || !utf8.ValidString(text) {
+		return "", fmt.Errorf("value of type '%c' is formatted as invalid text %q", v.Type, text)
+	}
+	return text, nil
+}
@@ -0,0 +1,133 @@
+package tdat
+
+import (
+	"fmt"
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+)
+
+type geoPoint struct {
+	lat, lon float64
+}
+
+func init() {
+	RegisterType('g', func(s string) (interface{}, error) {
+		var p geoPoint
+		_, err := fmt.Sscanf(s, "%f/%f", &p.lat, &p.lon)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geo point")
+		}
+		return p, nil
+	}, func(v interface{}) string {
+		p := v.(geoPoint)
+		return fmt.Sprintf("%g/%g", p.lat, p.lon)
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	input := "places\n|name:s|at:g|route:[g]\n|\"home\"|48.1/11.5|[1/2, 3/4]\n|\"nowhere\"||[]\n"
+	model, err := ParseOptions{Strict: true, Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	places := model.Tables[0]
+	assert.True(t, places.CustomAt(0, 1) == geoPoint{48.1, 11.5})
+	assert.True(t, places.IsNull(1, 1))
+	assert.True(t, places.CustomAt(1, 1) == nil)
+	assert.True(t, places.ListAt(0, 2)[1].AsCustom == geoPoint{3, 4})
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "places\n|name:s|at:g|route:[g]\n|\"home\"|48.1/11.5|[1/2,3/4]\n|\"nowhere\"||[]\n\n", txt)
+	// parse errors
+	_, err = ParseFromString("places\n|at:g\n|x\n")
+	assert.EqStr(t, "line 3, pos 2: cannot parse as 'g': invalid geo point", err.Error())
+	_, err = ParseOptions{Strict: true}.ParseFromString("places\n|at:g\n|\"1/2\"\n")
+	assert.EqStr(t, "line 3, pos 2: value must not be quoted", err.Error())
+	_, err = ParseFromString("places\n|at:h\n")
+	assert.EqStr(t, "line 2, pos 2: invalid column type", err.Error())
+	// builder
+	builder := NewBuilder()
+	table := builder.AddTable("places")
+	table.AddColumn("at", 'g')
+	table.AddRow().AddValue('g', geoPoint{1, 2})
+	table.AddRow().AddValue('g', nil)
+	model, err = builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "places\n|at:g\n|1/2\n|\n\n", txt)
+}
+
+func TestRegisterTypeInvalidText(t *testing.T) {
+	RegisterType('X', func(s string) (interface{}, error) {
+		return s, nil
+	}, func(v interface{}) string {
+		return v.(string)
+	})
+	defer func() {
+		customTypesMu.Lock()
+		delete(customTypes, 'X')
+		customTypesMu.Unlock()
+	}()
+	testCases := []struct {
+		text string
+		exp  string
+	}{
+		{"ok", ""},
+		{"", "value of type 'X' is formatted as invalid text \"\""},
+		{" a", "value of type 'X' is formatted as invalid text \" a\""},
+		{"a|b", "value of type 'X' is formatted as invalid text \"a|b\""},
+		{"\"a", "value of type 'X' is formatted as invalid text \"\\\"a\""},
+		{"[a", "value of type 'X' is formatted as invalid text \"[a\""},
+	}
+	for _, testCase := range testCases {
+		table := &Table{Name: "t", Columns: []*Column{{Name: "c", Type: 'X'}}, Rows: []*Row{
+			{Values: []*Value{{Type: 'X', AsCustom: testCase.text}}},
+		}}
+		err := ValidateTable(table)
+		act := ""
+		if err != nil {
+			act = err.Error()
+		}
+		exp := testCase.exp
+		if exp != "" {
+			exp = "row 1, value 1: " + exp
+		}
+		assert.EqStrf(t, exp, act, "text %q", testCase.text)
+		_, err = RenderToString(&Model{Tables: []*Table{table}}, 0)
+		act = ""
+		if err != nil {
+			act = err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "text %q", testCase.text)
+	}
+	// list elements must not contain commas
+	table := &Table{Name: "t", Columns: []*Column{{Name: "c", Type: ListValue, ElemType: 'X'}}, Rows: []*Row{
+		{Values: []*Value{{Type: ListValue, AsCustom: []*Value{{Type: 'X', AsCustom: "a,b"}}}}},
+	}}
+	err := ValidateTable(table)
+	assert.EqStr(t, "row 1, value 1: element 1: value of type 'X' is formatted as invalid text \"a,b\"", err.Error())
+}
+
+func TestRegisterTypePanics(t *testing.T) {
+	parse := func(s string) (interface{}, error) { return s, nil }
+	format := func(v interface{}) string { return v.(string) }
+	testCases := []struct {
+		code ValueType
+		exp  string
+	}{
+		{'g', "tdat: type code 'g' is already in use"},
+		{'i', "tdat: type code 'i' is already in use"},
+		{'D', "tdat: type code 'D' is already in use"},
+		{'1', "tdat: invalid type code '1'"},
+		{'[', "tdat: invalid type code '['"},
+	}
+	for _, testCase := range testCases {
+		act := func() (msg string) {
+			defer func() {
+				msg = fmt.Sprintf("%v", recover())
+			}()
+			RegisterType(testCase.code, parse, format)
+			return ""
+		}()
+		assert.EqStrf(t, testCase.exp, act, "code %c", testCase.code)
+	}
+}
@@ -2,15 +2,67 @@ package tdat
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // RenderToString is like RenderToWriter but renders to a string.
 func RenderToString(model *Model, colWidth int) (string, error) {
+	return RenderOptions{ColWidth: colWidth}.RenderToString(model)
+}
+
+// RenderToFile is like RenderToWriter but renders into a file.
+// If the file exists, it is overwritten.
+func RenderToFile(model *Model, colWidth int, filename string) error {
+	return RenderOptions{ColWidth: colWidth}.RenderToFile(model, filename)
+}
+
+// RenderToWriter renders a model to a io.Writer.
+// The renderer pads columns with spaces, so that each column
+// has at least colWidth characters.
+// If colWidth <= 0, no padding is applied.
+func RenderToWriter(model *Model, colWidth int, w io.Writer) error {
+	return RenderOptions{ColWidth: colWidth}.RenderToWriter(model, w)
+}
+
+// RenderWithContext is like RenderToWriter but stops rendering when ctx
+// is done. In that case, it returns ctx.Err().
+func RenderWithContext(ctx context.Context, model *Model, colWidth int, w io.Writer) error {
+	return RenderOptions{ColWidth: colWidth}.RenderWithContext(ctx, model, w)
+}
+
+// RenderOptions control how models are rendered.
+type RenderOptions struct {
+	// ColWidth pads columns with spaces, so that each column
+	// has at least ColWidth characters.
+	// If ColWidth <= 0, no padding is applied.
+	ColWidth int
+
+	// Progress, if not nil, is called periodically while rendering,
+	// and once at the end, with the number of bytes and rows written
+	// so far.
+	Progress func(bytes int64, rows int)
+
+	// ExactTimes renders time values with their UTC offset and with
+	// up to nanosecond precision, like "2001-01-02T10:11:12.000013+01:00".
+	// Times with offset zero are rendered without offset. By default,
+	// time values are converted to UTC and rendered with millisecond
+	// precision. Texts with offsets do not conform to rfc.txt and
+	// are rejected by strict parsers.
+	ExactTimes bool
+}
+
+// RenderToString is like the package-level function RenderToString
+// but uses the options in o.
+func (o RenderOptions) RenderToString(model *Model) (string, error) {
 	buffer := &bytes.Buffer{}
-	err := RenderToWriter(model, colWidth, buffer)
+	err := o.RenderToWriter(model, buffer)
 	if err != nil {
 		return "", err
 	}
@@ -18,63 +70,91 @@ func RenderToString(model *Model, colWidth int) (string, error) {
 	return txt, nil
 }
 
-// RenderToFile is like RenderToWriter but renders into a file.
-// If the file exists, it is overwritten.
-func RenderToFile(model *Model, colWidth int, filename string) error {
+// RenderToFile is like the package-level function RenderToFile
+// but uses the options in o.
+func (o RenderOptions) RenderToFile(model *Model, filename string) error {
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	return RenderToWriter(model, colWidth, file)
+	return o.RenderToWriter(model, file)
 }
 
-// RenderToWriter renders a model to a io.Writer.
-// The renderer pads columns with spaces, so that each column
-// has at least colWidth characters.
-// If colWidth <= 0, no padding is applied.
-func RenderToWriter(model *Model, colWidth int, w io.Writer) error {
-	r := renderer{w: w, colWidth: colWidth}
+// RenderToWriter is like the package-level function RenderToWriter
+// but uses the options in o.
+func (o RenderOptions) RenderToWriter(model *Model, w io.Writer) error {
+	return o.RenderWithContext(context.Background(), model, w)
+}
+
+// RenderWithContext is like the package-level function RenderWithContext
+// but uses the options in o.
+func (o RenderOptions) RenderWithContext(ctx context.Context, model *Model, w io.Writer) error {
+	r := &renderer{w: w, colWidth: o.ColWidth, ctx: ctx, progress: o.Progress, exactTimes: o.ExactTimes}
 	r.renderModel(model)
+	r.checkpoint()
 	return r.err
 }
 
 // ------------------------------------------------------------
 
 type renderer struct {
-	w        io.Writer
-	colWidth int
-	err      error
+	w          io.Writer
+	colWidth   int
+	exactTimes bool
+	ctx        context.Context
+	progress   func(bytes int64, rows int)
+	bytes      int64
+	rows       int
+	err        error
 }
 
-func (r renderer) renderModel(model *Model) {
+func (r *renderer) renderModel(model *Model) {
 	for _, table := range model.Tables {
 		r.renderTable(table)
 	}
+	r.renderComments(model.Comments)
 }
 
-func (r renderer) renderTable(table *Table) {
+func (r *renderer) renderTable(table *Table) {
 	if r.err != nil {
 		return
 	}
 	// table name
+	r.checkText(table.Name, "table %q", table.Name)
+	if strings.HasPrefix(table.Name, string(byteOrderMark)) {
+		r.fail("table %q: name starts with byte order mark", table.Name)
+	}
+	if strings.HasPrefix(table.Name, "#") {
+		r.fail("table %q: name starts with '#'", table.Name)
+	}
+	r.renderComments(table.Comments)
 	r.printf("%s\n", table.Name)
 	// columns
 	r.renderColumns(table.Columns)
 	// rows
-	for _, row := range table.Rows {
+	table.forEachRow(func(rowIndex int, row *Row) bool {
 		if r.err != nil {
-			return
+			return false
 		}
 		r.renderRow(row)
-	}
+		r.rows++
+		if r.rows%progressInterval == 0 {
+			r.checkpoint()
+		}
+		return true
+	})
 	r.printf("\n")
 }
 
-func (r renderer) renderColumns(columns []*Column) {
+func (r *renderer) renderColumns(columns []*Column) {
+	for _, col := range columns {
+		r.renderComments(col.Comments)
+	}
 	colCount := len(columns)
 	for colIndex, col := range columns {
-		cell := fmt.Sprintf("%s:%c", col.Name, col.Type)
+		r.checkText(col.Name, "column %q", col.Name)
+		cell := formatColumn(col)
 		if r.colWidth <= 0 || colIndex >= colCount-1 {
 			r.printf("|%s", cell)
 		} else {
@@ -86,26 +166,14 @@ func (r renderer) renderColumns(columns []*Column) {
 	}
 }
 
-func (r renderer) renderRow(row *Row) {
+func (r *renderer) renderRow(row *Row) {
+	r.renderComments(row.Comments)
 	valCount := len(row.Values)
 	for valIndex, val := range row.Values {
-		cell := ""
 		if !val.Null {
-			switch val.Type {
-			case IntValue:
-				cell = fmt.Sprintf("%d", val.AsInt)
-			case FloatValue:
-				cell = fmt.Sprintf("%f", val.AsFloat)
-			case BoolValue:
-				cell = fmt.Sprintf("%t", val.AsBool)
-			case StringValue:
-				cell = fmt.Sprintf("%q", val.AsString)
-			case TimeValue:
-				cell = val.AsTime.UTC().Format("2006-01-02T15:04:05.999")
-			default:
-				panic("wrong value type")
-			}
+			r.checkValue(val, false)
 		}
+		cell := formatValue(val, r.exactTimes)
 		if r.colWidth <= 0 || valIndex >= valCount-1 {
 			r.printf("|%s", cell)
 		} else {
@@ -117,12 +185,189 @@ func (r renderer) renderRow(row *Row) {
 	}
 }
 
-func (r renderer) printf(format string, args ...interface{}) {
+// renderComments renders comment lines, see rfc.txt, section 2.
+// Comments must not contain line breaks.
+func (r *renderer) renderComments(comments []string) {
+	for _, comment := range comments {
+		r.checkText(comment, "comment %q", comment)
+		if strings.ContainsAny(comment, "\r\n") {
+			r.fail("comment %q: contains line break", comment)
+		}
+		if comment == "" {
+			r.printf("#\n")
+		} else {
+			r.printf("# %s\n", comment)
+		}
+	}
+}
+
+// formatTime formats a time value. Without exactTimes, it is
+// converted to UTC and formatted with millisecond precision.
+func formatTime(t time.Time, exactTimes bool) string {
+	if !exactTimes {
+		return t.UTC().Format("2006-01-02T15:04:05.999")
+	}
+	_, offset := t.Zone()
+	if offset%60 != 0 {
+		// offsets with seconds, like in some historic
+		// zones, cannot be written as +hh:mm
+		t = t.UTC()
+		offset = 0
+	}
+	if offset == 0 {
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format("2006-01-02T15:04:05.999999999-07:00")
+}
+
+// formatColumn formats a column definition as a header cell.
+func formatColumn(col *Column) string {
+	switch {
+	case col.Type == EnumValue:
+		return fmt.Sprintf("%s:e(%s)", col.Name, strings.Join(col.Symbols, ","))
+	case col.Type == ListValue && col.ElemType == EnumValue:
+		return fmt.Sprintf("%s:[e(%s)]", col.Name, strings.Join(col.Symbols, ","))
+	case col.Type == ListValue:
+		return fmt.Sprintf("%s:[%c]", col.Name, col.ElemType)
+	}
+	return fmt.Sprintf("%s:%c", col.Name, col.Type)
+}
+
+// formatValue formats a value as a cell text. A null value
+// is formatted as empty text. For exactTimes, see RenderOptions.
+func formatValue(val *Value, exactTimes bool) string {
+	if val.Null {
+		return ""
+	}
+	switch val.Type {
+	case IntValue:
+		return fmt.Sprintf("%d", val.AsInt)
+	case FloatValue:
+		return fmt.Sprintf("%f", val.AsFloat)
+	case BoolValue:
+		return fmt.Sprintf("%t", val.AsBool)
+	case StringValue:
+		return quoteString(val.AsString)
+	case TimeValue:
+		return formatTime(val.AsTime, exactTimes)
+	case DecimalValue:
+		return val.Decimal().String()
+	case DateValue:
+		return val.AsTime.Format("2006-01-02")
+	case DurationValue:
+		return FormatDuration(val.Duration())
+	case BytesValue:
+		return "0x" + hex.EncodeToString(val.Bytes())
+	case UUIDValue:
+		return val.UUID().String()
+	case EnumValue:
+		return val.AsString
+	case ListValue:
+		return formatList(val, exactTimes)
+	}
+	// the text of a custom value is checked by the renderer
+	// and by validateValue
+	text, _ := formatCustom(val, false)
+	return text
+}
+
+// quoteString quotes a string as defined in rfc.txt, section 3.4.
+// Quotation marks, reverse solidus and control characters are escaped,
+// all other characters are written as they are.
+func quoteString(s string) string {
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf = append(buf, '\\', '"')
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if r < 0x20 {
+				buf = append(buf, fmt.Sprintf("\\u%04x", r)...)
+			} else {
+				buf = append(buf, string(r)...)
+			}
+		}
+	}
+	buf = append(buf, '"')
+	return string(buf)
+}
+
+// checkValue fails the renderer if a non-null value cannot be
+// rendered as a cell, or as a list element if elem is true.
+func (r *renderer) checkValue(val *Value, elem bool) {
+	if err := checkCustom(val); err != nil {
+		r.fail("%s", err)
+		return
+	}
+	switch val.Type {
+	case StringValue:
+		r.checkText(val.AsString, "string value %q", val.AsString)
+	case ListValue:
+		for _, e := range val.List() {
+			r.checkValue(e, true)
+		}
+	default:
+		if !isBuiltinType(val.Type) {
+			if _, err := formatCustom(val, elem); err != nil {
+				r.fail("%s", err)
+			}
+		}
+	}
+}
+
+// checkText fails the renderer if s is not valid UTF-8, so that
+// the renderer never writes invalid TDAT text, see rfc.txt, section 4.1.
+func (r *renderer) checkText(s string, format string, args ...interface{}) {
+	if !utf8.ValidString(s) {
+		r.fail(format+": invalid UTF-8", args...)
+	}
+}
+
+// checkpoint fails the renderer if its context is done, and
+// reports progress.
+func (r *renderer) checkpoint() {
+	if r.err != nil {
+		return
+	}
+	if r.ctx != nil {
+		select {
+		case <-r.ctx.Done():
+			r.err = r.ctx.Err()
+			return
+		default:
+		}
+	}
+	if r.progress != nil {
+		r.progress(r.bytes, r.rows)
+	}
+}
+
+func (r *renderer) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *renderer) printf(format string, args ...interface{}) {
 	if r.err != nil {
 		return
 	}
 	s := fmt.Sprintf(format, args...)
-	_, err := r.w.Write([]byte(s))
+	n, err := r.w.Write([]byte(s))
+	r.bytes += int64(n)
 	if err != nil {
 		r.err = err
 	}
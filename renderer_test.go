@@ -1,9 +1,13 @@
 package tdat
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/cvilsmeier/tdat/assert"
 	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
 )
@@ -11,19 +15,19 @@ import (
 func TestRenderToString(t *testing.T) {
 	locBerlin, _ := time.LoadLocation("Europe/Berlin")
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"persons",
-				[]*Column{
-					{"id", IntValue},
-					{"size", FloatValue},
-					{"flag", BoolValue},
-					{"name", StringValue},
-					{"birth", TimeValue},
+				Name: "persons",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
+					{Name: "size", Type: FloatValue},
+					{Name: "flag", Type: BoolValue},
+					{Name: "name", Type: StringValue},
+					{Name: "birth", Type: TimeValue},
 				},
-				[]*Row{
+				Rows: []*Row{
 					{
-						[]*Value{
+						Values: []*Value{
 							{Type: IntValue, AsInt: int64(1)},
 							{Type: FloatValue, AsFloat: float64(1.83)},
 							{Type: BoolValue, AsBool: true},
@@ -32,7 +36,7 @@ func TestRenderToString(t *testing.T) {
 						},
 					},
 					{
-						[]*Value{
+						Values: []*Value{
 							{Type: IntValue, Null: true},
 							{Type: FloatValue, Null: true},
 							{Type: BoolValue, Null: true},
@@ -66,12 +70,199 @@ func TestRenderToString(t *testing.T) {
 	assert.EqStr(t, exp, txt)
 }
 
+func TestRenderExactTimes(t *testing.T) {
+	locBerlin, _ := time.LoadLocation("Europe/Berlin")
+	locAmsterdam, _ := time.LoadLocation("Europe/Amsterdam")
+	times := []time.Time{
+		time.Date(2001, 1, 2, 10, 11, 12, 13000, locBerlin),
+		time.Date(2001, 7, 2, 10, 11, 12, 0, locBerlin),
+		time.Date(2001, 1, 2, 10, 11, 12, 123456789, time.UTC),
+		time.Date(2001, 1, 2, 10, 11, 12, 0, time.FixedZone("", -90*60)),
+		time.Date(1900, 1, 2, 10, 11, 12, 0, locAmsterdam), // offset +00:19:32
+	}
+	builder := NewBuilder()
+	table := builder.AddTable("events")
+	table.AddTimeColumn("at")
+	table.AddListColumn("more", TimeValue)
+	for _, tm := range times {
+		row := table.AddRow()
+		row.AddTimeValue(tm)
+		row.AddListValue([]*Value{{Type: TimeValue, AsTime: tm}})
+	}
+	model, err := builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp := "events\n|at:t|more:[t]\n"
+	exp += "|2001-01-02T09:11:12|[2001-01-02T09:11:12]\n"
+	exp += "|2001-07-02T08:11:12|[2001-07-02T08:11:12]\n"
+	exp += "|2001-01-02T10:11:12.123|[2001-01-02T10:11:12.123]\n"
+	exp += "|2001-01-02T11:41:12|[2001-01-02T11:41:12]\n"
+	exp += "|1900-01-02T09:51:40|[1900-01-02T09:51:40]\n"
+	exp += "\n"
+	assert.EqStr(t, exp, txt)
+	// exact
+	var buf bytes.Buffer
+	err = RenderOptions{ExactTimes: true}.RenderWithContext(context.Background(), model, &buf)
+	assert.Truef(t, err == nil, "err was %s", err)
+	exp = "events\n|at:t|more:[t]\n"
+	exp += "|2001-01-02T10:11:12.000013+01:00|[2001-01-02T10:11:12.000013+01:00]\n"
+	exp += "|2001-07-02T10:11:12+02:00|[2001-07-02T10:11:12+02:00]\n"
+	exp += "|2001-01-02T10:11:12.123456789|[2001-01-02T10:11:12.123456789]\n"
+	exp += "|2001-01-02T10:11:12-01:30|[2001-01-02T10:11:12-01:30]\n"
+	exp += "|1900-01-02T09:51:40|[1900-01-02T09:51:40]\n"
+	exp += "\n"
+	assert.EqStr(t, exp, buf.String())
+	txt, err = RenderOptions{ExactTimes: true}.RenderToString(model)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, exp, txt)
+	// parse back
+	model, err = ParseFromString(buf.String())
+	assert.Truef(t, err == nil, "err was %s", err)
+	for i, tm := range times {
+		act := model.Tables[0].Rows[i].Values[0].AsTime
+		assert.Truef(t, tm.Equal(act), "row %d: expected %s but was %s", i, tm, act)
+		_, expOffset := tm.Zone()
+		_, actOffset := act.Zone()
+		if expOffset%60 == 0 {
+			assert.EqIntf(t, expOffset, actOffset, "row %d", i)
+		}
+		act = model.Tables[0].Rows[i].Values[1].List()[0].AsTime
+		assert.Truef(t, tm.Equal(act), "row %d: expected %s but was %s", i, tm, act)
+	}
+	_, err = ParseOptions{Strict: true}.ParseFromString(buf.String())
+	assert.EqStr(t, "line 3, pos 2: cannot parse as time: offset \"+01:00\" is not allowed", err.Error())
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	strs := []string{"", " ", "  padded  ", "\ttab\t", " \r\n ", "a|b", "\"quoted\""}
+	builder := NewBuilder()
+	table := builder.AddTable("codes")
+	table.AddIntColumn("id")
+	table.AddStringColumn("code")
+	for i, str := range strs {
+		row := table.AddRow()
+		row.AddIntValue(int64(i))
+		row.AddStringValue(str)
+	}
+	model := builder.MustBuild()
+	for _, colWidth := range []int{0, 12} {
+		txt, err := RenderToString(model, colWidth)
+		assert.Truef(t, err == nil, "err was %s", err)
+		parsed, err := ParseOptions{Strict: true}.ParseFromString(txt)
+		assert.Truef(t, err == nil, "err was %s", err)
+		rows := parsed.Tables[0].Rows
+		assert.EqInt(t, len(strs), len(rows))
+		for i, str := range strs {
+			assert.EqStr(t, str, rows[i].Values[1].AsString)
+		}
+	}
+}
+
+func TestRenderInvalidUTF8(t *testing.T) {
+	testCases := []struct {
+		table  string
+		column string
+		value  string
+		exp    string
+	}{
+		{"t\xff", "c", "v", "table \"t\\xff\": invalid UTF-8"},
+		{"\ufefft", "c", "v", "table \"\\ufefft\": name starts with byte order mark"},
+		{"t", "c\xff", "v", "column \"c\\xff\": invalid UTF-8"},
+		{"t", "c", "v\xff", "string value \"v\\xff\": invalid UTF-8"},
+		{"t", "c", "\ufeffv", ""},
+	}
+	for _, testCase := range testCases {
+		model := &Model{
+			Tables: []*Table{
+				{
+					Name:    testCase.table,
+					Columns: []*Column{{Name: testCase.column, Type: StringValue}},
+					Rows:    []*Row{{Values: []*Value{{Type: StringValue, AsString: testCase.value}}}},
+				},
+			},
+		}
+		_, err := RenderToString(model, 0)
+		act := ""
+		if err != nil {
+			act = err.Error()
+		}
+		assert.EqStr(t, testCase.exp, act)
+	}
+}
+
+func TestRenderComments(t *testing.T) {
+	input := "# reference data\n"
+	input += "#\n"
+	input += "persons\n"
+	input += "# the header\n"
+	input += "|id:i|name:s\n"
+	input += "|1|\"joe\"\n"
+	input += "#  indented\n"
+	input += "|2|\"#2\"\n"
+	input += "\n"
+	input += "# the end\n"
+	model, err := ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input, txt)
+	// comments must be single lines
+	model.Comments = []string{"the\nend"}
+	_, err = RenderToString(model, 0)
+	assert.EqStr(t, "comment \"the\\nend\": contains line break", err.Error())
+}
+
+func TestRenderWithContext(t *testing.T) {
+	model, err := ParseBytes(benchmarkInput(3000))
+	assert.Truef(t, err == nil, "err was %s", err)
+	// progress
+	progress := []string{}
+	opts := RenderOptions{Progress: func(bytes int64, rows int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", bytes, rows))
+	}}
+	buf := &bytes.Buffer{}
+	err = opts.RenderWithContext(context.Background(), model, buf)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, fmt.Sprintf("[52266/1024 104490/2048 %d/3000]", buf.Len()), fmt.Sprintf("%v", progress))
+	// cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = RenderWithContext(ctx, model, 0, ioutil.Discard)
+	assert.True(t, err == context.Canceled)
+}
+
+func TestQuoteString(t *testing.T) {
+	testCases := []struct {
+		input string
+		exp   string
+	}{
+		{"", `""`},
+		{"joe", `"joe"`},
+		{"a|b", `"a|b"`},
+		{"\"\\/", `"\"\\/"`},
+		{"\b\f\n\r\t", `"\b\f\n\r\t"`},
+		{"\x00\x01\x1f\x7f", `"\u0000\u0001\u001f` + "\x7f" + `"`},
+		{"\u2602 \U0001D11E", "\"\u2602 \U0001D11E\""},
+	}
+	for _, testCase := range testCases {
+		act := quoteString(testCase.input)
+		assert.EqStr(t, testCase.exp, act)
+		// parse it back
+		lex := newLexer(strings.NewReader(act))
+		lex.strict = true
+		text, err := lex.readQuotedText()
+		assert.Truef(t, err == nil, "err was %s", err)
+		assert.EqStr(t, testCase.input, string(text))
+	}
+}
+
 func BenchmarkRenderTdat(b *testing.B) {
 	rowCount := 100 * 1000
 	rows := make([]*Row, 0, rowCount)
 	for i := 0; i < rowCount; i++ {
 		row := &Row{
-			[]*Value{
+			Values: []*Value{
 				{Type: IntValue, AsInt: int64(1)},
 				{Type: FloatValue, AsFloat: float64(13000.12)},
 				{Type: BoolValue, AsBool: true},
@@ -81,16 +272,16 @@ func BenchmarkRenderTdat(b *testing.B) {
 		rows = append(rows, row)
 	}
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"persons",
-				[]*Column{
-					{"id", IntValue},
-					{"rate", FloatValue},
-					{"flag", BoolValue},
-					{"name", StringValue},
+				Name: "persons",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
+					{Name: "rate", Type: FloatValue},
+					{Name: "flag", Type: BoolValue},
+					{Name: "name", Type: StringValue},
 				},
-				rows,
+				Rows: rows,
 			},
 		},
 	}
@@ -0,0 +1,52 @@
+package tdat
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// A UUID is a universally unique identifier as defined in RFC 4122.
+type UUID [16]byte
+
+// ParseUUID parses a UUID in the canonical form of rfc.txt, section 3.10,
+// like "123e4567-e89b-12d3-a456-426614174000". Hex digits may be
+// uppercase or lowercase. Other than the grammar, it also accepts a UUID
+// without hyphens, in braces, or with a "urn:uuid:" prefix.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	text := s
+	if strings.HasPrefix(strings.ToLower(text), "urn:uuid:") {
+		text = text[len("urn:uuid:"):]
+	} else if len(text) >= 2 && text[0] == '{' && text[len(text)-1] == '}' {
+		text = text[1 : len(text)-1]
+	}
+	if len(text) == 36 {
+		if text[8] != '-' || text[13] != '-' || text[18] != '-' || text[23] != '-' {
+			return u, fmt.Errorf("parsing %q: invalid syntax", s)
+		}
+		text = text[0:8] + text[9:13] + text[14:18] + text[19:23] + text[24:]
+	}
+	if len(text) != 32 {
+		return u, fmt.Errorf("parsing %q: invalid syntax", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(text)); err != nil {
+		return u, fmt.Errorf("parsing %q: invalid syntax", s)
+	}
+	return u, nil
+}
+
+// String returns the UUID in canonical form, with lowercase hex digits.
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
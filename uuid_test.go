@@ -0,0 +1,61 @@
+package tdat
+
+import (
+	"github.com/cvilsmeier/tdat/assert"
+	"testing"
+)
+
+func TestParseUUID(t *testing.T) {
+	testCases := []struct {
+		input string
+		exp   string
+	}{
+		{"00000000-0000-0000-0000-000000000000", "00000000-0000-0000-0000-000000000000"},
+		{"123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{"123E4567-E89B-12D3-A456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{"{123e4567-e89b-12d3-a456-426614174000}", "123e4567-e89b-12d3-a456-426614174000"},
+		{"urn:uuid:123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{"", "err parsing \"\": invalid syntax"},
+		{"123e4567-e89b-12d3-a456_426614174000", "err parsing \"123e4567-e89b-12d3-a456_426614174000\": invalid syntax"},
+		{"123e4567-e89b-12d3-a456-42661417400g", "err parsing \"123e4567-e89b-12d3-a456-42661417400g\": invalid syntax"},
+		{"{123e4567e89b12d3a456426614174000", "err parsing \"{123e4567e89b12d3a456426614174000\": invalid syntax"},
+	}
+	for _, testCase := range testCases {
+		u, err := ParseUUID(testCase.input)
+		act := u.String()
+		if err != nil {
+			act = "err " + err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "input %q", testCase.input)
+	}
+}
+
+func TestParseBytesAndUUIDValues(t *testing.T) {
+	input := "files\n|id:U|sum:B\n|123e4567-e89b-12d3-a456-426614174000|0x00ff\n||0x\n|00000000-0000-0000-0000-000000000000|\n"
+	model, err := ParseOptions{Strict: true, Columnar: true}.ParseFromString(input)
+	assert.Truef(t, err == nil, "err was %s", err)
+	files := model.Tables[0]
+	assert.EqStr(t, "123e4567-e89b-12d3-a456-426614174000", files.UUIDAt(0, 0).String())
+	assert.EqInt(t, 2, len(files.BytesAt(0, 1)))
+	assert.True(t, files.IsNull(1, 0))
+	assert.True(t, !files.IsNull(1, 1))
+	assert.EqInt(t, 0, len(files.BytesAt(1, 1)))
+	assert.True(t, files.IsNull(2, 1))
+	txt, err := RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, input+"\n", txt)
+	// builder
+	builder := NewBuilder()
+	table := builder.AddTable("files")
+	table.AddUUIDColumn("id")
+	table.AddBytesColumn("sum")
+	row := table.AddRow()
+	row.AddUUIDValue(UUID{15: 1})
+	row.AddBytesValue([]byte("hi"))
+	model, err = builder.Build()
+	assert.Truef(t, err == nil, "err was %s", err)
+	txt, err = RenderToString(model, 0)
+	assert.Truef(t, err == nil, "err was %s", err)
+	assert.EqStr(t, "files\n|id:U|sum:B\n|00000000-0000-0000-0000-000000000001|0x6869\n\n", txt)
+}
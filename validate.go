@@ -3,6 +3,8 @@ package tdat
 import (
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // ValidateModel validates a model. If the model is invalid, it returns a
@@ -31,9 +33,21 @@ func ValidateModel(model *Model) error {
 // ValidateTable validates a table. If the table is invalid, it returns a
 // non-nil error.
 func ValidateTable(table *Table) error {
-	// validate columns
+	err := validateColumns(table.Columns)
+	if err != nil {
+		return err
+	}
+	table.forEachRow(func(rowIndex int, row *Row) bool {
+		err = validateRow(table.Columns, rowIndex, row)
+		return err == nil
+	})
+	return err
+}
+
+// validateColumns validates the columns of a table.
+func validateColumns(columns []*Column) error {
 	columnNames := map[string]bool{}
-	for _, column := range table.Columns {
+	for _, column := range columns {
 		// validate name
 		err := ValidateName(column.Name)
 		if err != nil {
@@ -48,37 +62,161 @@ func ValidateTable(table *Table) error {
 		if !column.Type.IsValid() {
 			return fmt.Errorf("column %q has invalid type '%c'", column.Name, ct)
 		}
+		// validate element type
+		if ct == ListValue {
+			et := column.ElemType
+			if !et.IsValid() || et == ListValue {
+				return fmt.Errorf("column %q has invalid element type '%c'", column.Name, et)
+			}
+			ct = et
+		} else if column.ElemType != 0 {
+			return fmt.Errorf("column %q: element type is only allowed for type '['", column.Name)
+		}
+		// validate symbols
+		if ct == EnumValue {
+			err = validateSymbols(column.Symbols)
+			if err != nil {
+				return fmt.Errorf("column %q: %s", column.Name, err)
+			}
+		} else if len(column.Symbols) > 0 {
+			return fmt.Errorf("column %q: symbols are only allowed for type 'e'", column.Name)
+		}
+	}
+	return nil
+}
+
+// validateRow validates the values of a row against the columns of
+// a table. The rowIndex is zero-based.
+func validateRow(columns []*Column, rowIndex int, row *Row) error {
+	colCount := len(columns)
+	valCount := len(row.Values)
+	if valCount != colCount {
+		return fmt.Errorf("row %d: expected %d values but got %d", rowIndex+1, colCount, valCount)
+	}
+	for valueIndex, value := range row.Values {
+		column := columns[valueIndex]
+		if value == nil {
+			return fmt.Errorf("row %d, value %d: value is nil", rowIndex+1, valueIndex+1)
+		}
+		if value.Type != column.Type {
+			return fmt.Errorf("row %d, value %d: expected value type '%c' but was '%c'", rowIndex+1, valueIndex+1, column.Type, value.Type)
+		}
+		err := validateValue(column, value)
+		if err != nil {
+			return fmt.Errorf("row %d, value %d: %s", rowIndex+1, valueIndex+1, err)
+		}
+	}
+	return nil
+}
+
+// validateValue validates a value of the type of column. Null values
+// are valid. The elements of a list must not be null. Strings and
+// enums must be valid UTF-8, see rfc.txt, section 4.1.
+func validateValue(column *Column, value *Value) error {
+	if value.Null {
+		return nil
+	}
+	if err := checkCustom(value); err != nil {
+		return err
+	}
+	switch value.Type {
+	case StringValue:
+		if !utf8.ValidString(value.AsString) {
+			return fmt.Errorf("string %q contains invalid UTF-8", value.AsString)
+		}
+	case DateValue:
+		if !isMidnight(value.AsTime) {
+			return fmt.Errorf("date has a time of day")
+		}
+	case EnumValue:
+		if !utf8.ValidString(value.AsString) {
+			return fmt.Errorf("enum %q contains invalid UTF-8", value.AsString)
+		}
+		if _, ok := column.symbol([]byte(value.AsString)); !ok {
+			return fmt.Errorf("%q is not a symbol of column %q", value.AsString, column.Name)
+		}
+	case ListValue:
+		for k, elem := range value.List() {
+			if elem == nil || elem.Null {
+				return fmt.Errorf("element %d is null", k+1)
+			}
+			if elem.Type != column.ElemType {
+				return fmt.Errorf("element %d: expected value type '%c' but was '%c'", k+1, column.ElemType, elem.Type)
+			}
+			err := validateValue(column, elem)
+			if err == nil && !isBuiltinType(elem.Type) {
+				_, err = formatCustom(elem, true)
+			}
+			if err != nil {
+				return fmt.Errorf("element %d: %s", k+1, err)
+			}
+		}
+	default:
+		if !isBuiltinType(value.Type) {
+			_, err := formatCustom(value, false)
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSymbols validates the symbols of an EnumValue column,
+// see rfc.txt, section 3.11. There must be at least one symbol,
+// and symbols must be unique.
+func validateSymbols(symbols []string) error {
+	if len(symbols) == 0 {
+		return fmt.Errorf("enum has no symbols")
 	}
-	colCount := len(table.Columns)
-	// validate rows
-	for rowIndex, row := range table.Rows {
-		valCount := len(row.Values)
-		if valCount != colCount {
-			return fmt.Errorf("row %d: expected %d values but got %d", rowIndex+1, colCount, valCount)
-		}
-		for valueIndex, value := range row.Values {
-			column := table.Columns[valueIndex]
-			if value.Type != column.Type {
-				return fmt.Errorf("row %d, value %d: expected value type '%c' but was '%c'", rowIndex+1, valueIndex+1, column.Type, value.Type)
+	seen := map[string]bool{}
+	for _, s := range symbols {
+		if s == "" {
+			return fmt.Errorf("symbol is empty")
+		}
+		if !utf8.ValidString(s) {
+			return fmt.Errorf("symbol %q contains invalid UTF-8", s)
+		}
+		for _, r := range s {
+			if r <= ' ' || r == byteOrderMark || strings.ContainsRune(",()[]|\"", r) {
+				return fmt.Errorf("symbol %q contains invalid character '%c'", s, r)
 			}
 		}
+		if seen[s] {
+			return fmt.Errorf("duplicate symbol %q", s)
+		}
+		seen[s] = true
 	}
 	return nil
 }
 
+// isMidnight reports whether t is at midnight, in the location of t.
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
 // ValidateName validates a table or column name.
 // If the name is not valid, it returns a non-nil error.
+// A name must not start with '#', since a line that starts
+// with '#' is a comment, see rfc.txt, section 2.
 func ValidateName(name string) error {
 	if len(name) == 0 {
 		return fmt.Errorf("name is empty")
 	}
+	if name[0] == '#' {
+		return fmt.Errorf("name starts with '#'")
+	}
 	if name != strings.TrimSpace(name) {
 		return fmt.Errorf("name contains whitespace")
 	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("name contains invalid UTF-8")
+	}
 	for _, r := range name {
 		if r <= ' ' {
 			return fmt.Errorf("name contains invalid character '%c'", r)
 		}
+		if r == byteOrderMark {
+			return fmt.Errorf("name contains byte order mark")
+		}
 	}
 	return nil
 }
@@ -3,17 +3,18 @@ package tdat
 import (
 	"github.com/cvilsmeier/tdat/assert"
 	"testing"
+	"time"
 )
 
 func TestValidateModelNoValues(t *testing.T) {
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"products",
-				[]*Column{
-					{"id", IntValue},
+				Name: "products",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
 				},
-				[]*Row{
+				Rows: []*Row{
 					{},
 				},
 			},
@@ -26,16 +27,16 @@ func TestValidateModelNoValues(t *testing.T) {
 
 func TestValidateTooManyValues(t *testing.T) {
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"products",
-				[]*Column{
-					{"id", IntValue},
-					{"name", StringValue},
+				Name: "products",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
+					{Name: "name", Type: StringValue},
 				},
-				[]*Row{
+				Rows: []*Row{
 					{
-						[]*Value{
+						Values: []*Value{
 							{Type: IntValue},
 							{Type: StringValue},
 							{Type: BoolValue},
@@ -52,16 +53,16 @@ func TestValidateTooManyValues(t *testing.T) {
 
 func TestValidateWrongValueType(t *testing.T) {
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"products",
-				[]*Column{
-					{"id", IntValue},
-					{"name", StringValue},
+				Name: "products",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
+					{Name: "name", Type: StringValue},
 				},
-				[]*Row{
+				Rows: []*Row{
 					{
-						[]*Value{
+						Values: []*Value{
 							{Type: IntValue},
 							{Type: BoolValue},
 						},
@@ -75,18 +76,143 @@ func TestValidateWrongValueType(t *testing.T) {
 	assert.EqStr(t, "table \"products\": row 1, value 2: expected value type 's' but was 'b'", err.Error())
 }
 
+func TestValidateDateWithTimeOfDay(t *testing.T) {
+	model := &Model{
+		Tables: []*Table{
+			{
+				Name: "persons",
+				Columns: []*Column{
+					{Name: "born", Type: DateValue},
+				},
+				Rows: []*Row{
+					{Values: []*Value{{Type: DateValue, AsTime: time.Date(1972, 5, 3, 0, 0, 0, 0, time.UTC)}}},
+					{Values: []*Value{{Type: DateValue, Null: true}}},
+					{Values: []*Value{{Type: DateValue, AsTime: time.Date(1972, 5, 3, 0, 0, 0, 1, time.UTC)}}},
+				},
+			},
+		},
+	}
+	err := ValidateModel(model)
+	assert.True(t, err != nil)
+	assert.EqStr(t, "table \"persons\": row 3, value 1: date has a time of day", err.Error())
+}
+
+func TestValidateInvalidUTF8(t *testing.T) {
+	testCases := []struct {
+		value *Value
+		exp   string
+	}{
+		{&Value{Type: StringValue, AsString: "a\xffb"}, "table \"t\": row 1, value 1: string \"a\\xffb\" contains invalid UTF-8"},
+		{&Value{Type: EnumValue, AsString: "a\xff"}, "table \"t\": row 1, value 2: enum \"a\\xff\" contains invalid UTF-8"},
+		{&Value{Type: ListValue, AsCustom: []*Value{{Type: StringValue, AsString: "\xff"}}}, "table \"t\": row 1, value 3: element 1: string \"\\xff\" contains invalid UTF-8"},
+	}
+	for _, testCase := range testCases {
+		values := []*Value{
+			{Type: StringValue, AsString: "a"},
+			{Type: EnumValue, AsString: "a"},
+			{Type: ListValue},
+		}
+		for i := range values {
+			if values[i].Type == testCase.value.Type {
+				values[i] = testCase.value
+			}
+		}
+		model := &Model{
+			Tables: []*Table{
+				{
+					Name: "t",
+					Columns: []*Column{
+						{Name: "s", Type: StringValue},
+						{Name: "e", Type: EnumValue, Symbols: []string{"a"}},
+						{Name: "l", Type: ListValue, ElemType: StringValue},
+					},
+					Rows: []*Row{{Values: values}},
+				},
+			},
+		}
+		err := ValidateModel(model)
+		assert.True(t, err != nil)
+		assert.EqStr(t, testCase.exp, err.Error())
+	}
+}
+
+func TestValidateCustom(t *testing.T) {
+	testCases := []struct {
+		value *Value
+		exp   string
+	}{
+		{&Value{Type: DecimalValue, AsCustom: NewDecimal(15, 1)}, ""},
+		{&Value{Type: DecimalValue, AsCustom: "1.5"}, "value of type 'd' holds string"},
+		{&Value{Type: BytesValue}, ""},
+		{&Value{Type: BytesValue, AsCustom: []byte{1}}, ""},
+		{&Value{Type: BytesValue, AsCustom: "0x01"}, "value of type 'B' holds string"},
+		{&Value{Type: UUIDValue, AsCustom: UUID{}}, ""},
+		{&Value{Type: UUIDValue}, "value of type 'U' holds <nil>"},
+		{&Value{Type: ListValue}, ""},
+		{&Value{Type: ListValue, AsCustom: []Value{}}, "value of type '[' holds []tdat.Value"},
+	}
+	for _, testCase := range testCases {
+		column := &Column{Name: "c", Type: testCase.value.Type}
+		if column.Type == ListValue {
+			column.ElemType = IntValue
+		}
+		model := &Model{Tables: []*Table{{Name: "t", Columns: []*Column{column}, Rows: []*Row{{Values: []*Value{testCase.value}}}}}}
+		act := ""
+		if err := ValidateModel(model); err != nil {
+			act = err.Error()
+		}
+		exp := testCase.exp
+		if exp != "" {
+			exp = "table \"t\": row 1, value 1: " + exp
+		}
+		assert.EqStrf(t, exp, act, "value %v", testCase.value.AsCustom)
+		// the renderer does not panic
+		act = ""
+		if _, err := RenderToString(model, 0); err != nil {
+			act = err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "value %v", testCase.value.AsCustom)
+	}
+}
+
+func TestValidateSymbols(t *testing.T) {
+	testCases := []struct {
+		column *Column
+		exp    string
+	}{
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a", "b"}}, ""},
+		{&Column{Name: "c", Type: EnumValue}, "column \"c\": enum has no symbols"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a", ""}}, "column \"c\": symbol is empty"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a", "a"}}, "column \"c\": duplicate symbol \"a\""},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a b"}}, "column \"c\": symbol \"a b\" contains invalid character ' '"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a,b"}}, "column \"c\": symbol \"a,b\" contains invalid character ','"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"(a)"}}, "column \"c\": symbol \"(a)\" contains invalid character '('"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"a|b"}}, "column \"c\": symbol \"a|b\" contains invalid character '|'"},
+		{&Column{Name: "c", Type: EnumValue, Symbols: []string{"\"a\""}}, "column \"c\": symbol \"\\\"a\\\"\" contains invalid character '\"'"},
+		{&Column{Name: "c", Type: StringValue, Symbols: []string{"a"}}, "column \"c\": symbols are only allowed for type 'e'"},
+	}
+	for _, testCase := range testCases {
+		err := validateColumns([]*Column{testCase.column})
+		act := ""
+		if err != nil {
+			act = err.Error()
+		}
+		assert.EqStrf(t, testCase.exp, act, "symbols %v", testCase.column.Symbols)
+	}
+}
+
 func TestValidateOk(t *testing.T) {
 	model := &Model{
-		[]*Table{
+		Tables: []*Table{
 			{
-				"products",
-				[]*Column{
-					{"id", IntValue},
-					{"name", StringValue},
+				Name: "products",
+				Columns: []*Column{
+					{Name: "id", Type: IntValue},
+					{Name: "name", Type: StringValue},
 				},
-				[]*Row{
+				Rows: []*Row{
 					{
-						[]*Value{
+						Values: []*Value{
 							{Type: IntValue},
 							{Type: StringValue},
 						},
@@ -98,3 +224,27 @@ func TestValidateOk(t *testing.T) {
 	err := ValidateModel(model)
 	assert.Truef(t, err == nil, "err was %s", err)
 }
+
+func TestValidateName(t *testing.T) {
+	testCases := []struct {
+		name string
+		exp  string
+	}{
+		{"persons", ""},
+		{"\u2602", ""},
+		{"", "name is empty"},
+		{"a b", "name contains invalid character ' '"},
+		{"a\xffb", "name contains invalid UTF-8"},
+		{"\ufeffa", "name contains byte order mark"},
+		{"#a", "name starts with '#'"},
+		{"a#", ""},
+	}
+	for _, testCase := range testCases {
+		err := ValidateName(testCase.name)
+		act := ""
+		if err != nil {
+			act = err.Error()
+		}
+		assert.EqStr(t, testCase.exp, act)
+	}
+}